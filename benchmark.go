@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+// benchmarkResult is what one model produced for the diff
+type benchmarkResult struct {
+	model   string
+	message string
+	latency time.Duration
+	err     error
+}
+
+func runBenchmark(args []string) {
+	fs := newFlagSet(findCommand(commands, "benchmark"), "benchmark")
+	modelList := fs.String("models", "", "Comma-separated models to compare, e.g. gemma3:1b,llama3,qwen2.5")
+	noConfirm := fs.Bool("y", false, "Don't ask before sending the changes to a remote API")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var models []string
+	for _, model := range strings.Split(*modelList, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: give the models to compare with -models, e.g. -models gemma3:1b,llama3")
+		os.Exit(2)
+	}
+
+	config := loadConfig()
+	for _, model := range models {
+		if err := cmd.CheckModelAllowed(model, config.AllowedModels); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	generator := modelsGenerator(config)
+	client, provider := generator.Client(), generator.Provider()
+
+	staged, err := cmd.GetStagedChanges()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	if len(staged) == 0 {
+		fmt.Println("No staged changes; stage the changes to compare the models on")
+		os.Exit(0)
+	}
+	diffOpts := cmd.DiffOptions{
+		FindRenames:      config.FindRenames,
+		FindCopies:       config.FindCopies,
+		Plumbing:         config.Plumbing,
+		IgnoreWhitespace: config.IgnoreWhitespace,
+		Exclude:          config.GeneratedFiles,
+	}
+	diff, err := cmd.GetGitDiff(diffOpts)
+	if errors.Is(err, cmd.ErrNoChanges) {
+		fmt.Println("No changes to commit")
+		os.Exit(exitNoChanges)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting git diff: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	if !*noConfirm {
+		confirmSendingChanges(provider, config.OllamaAPIURL, config)
+	}
+
+	// Clean up the messages and commit the same way generate does
+	config.PromptTemplate = withFinishInstructions(config.PromptTemplate, config)
+	finish := finishOptions(config, func() ([]string, error) { return cmd.GetChangedFiles(diffOpts) })
+
+	// One model at a time, so they don't compete for the server
+	results := make([]benchmarkResult, len(models))
+	for i, model := range models {
+		fmt.Fprintf(os.Stderr, "Generating with %s (%d/%d)...\n", model, i+1, len(models))
+		var options *cmd.Options
+		if defaults, ok := config.ModelDefaults[model]; ok {
+			options = &defaults
+		}
+		start := time.Now()
+		message, err := client.GenerateCommitMessage(provider, diff, model, config.PromptTemplate, options)
+		results[i] = benchmarkResult{model: model, latency: time.Since(start), err: err}
+		if err == nil {
+			var notes []string
+			results[i].message, notes, results[i].err = cmd.FinishMessage(message, finish)
+			for _, note := range notes {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", model, note)
+			}
+		}
+	}
+
+	// The subjects side by side, then the full messages
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tMODEL\tTIME\tSUBJECT")
+	for i, result := range results {
+		subject := ""
+		if result.err != nil {
+			subject = "error: " + result.err.Error()
+		} else {
+			subject, _ = cmd.SplitMessage(result.message, cmd.SeparatorBlankLine)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, result.model, result.latency.Round(100*time.Millisecond), subject)
+	}
+	w.Flush()
+	for i, result := range results {
+		if result.err == nil {
+			printMessage(fmt.Sprintf("[%d] %s:", i+1, result.model), cmd.ApplyLineEnding(result.message, finish.LineEnding))
+		}
+	}
+
+	answer, err := cmd.ReadLine(fmt.Sprintf("Commit with which message? (1-%d, Enter to skip): ", len(results)))
+	if err != nil || answer == "" {
+		recordBenchmark(config, results, diff, -1)
+		return
+	}
+	choice, err := strconv.Atoi(answer)
+	if err != nil || choice < 1 || choice > len(results) || results[choice-1].err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %q is not one of the generated messages\n", answer)
+		os.Exit(1)
+	}
+	picked := results[choice-1]
+	if err := commitMessage(picked.message, finish.LineEnding, config.TranscodeMessage, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error executing git commit: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Changes committed with the message of %s\n", picked.model)
+	recordBenchmark(config, results, diff, choice-1)
+}
+
+// recordBenchmark records the generated messages for the stats and history
+// commands, the one committed with as accepted
+func recordBenchmark(config cmd.Config, results []benchmarkResult, diff string, picked int) {
+	if config.DisableStats {
+		return
+	}
+	for i, result := range results {
+		if result.err != nil {
+			continue
+		}
+		outcome := cmd.OutcomeShown
+		if i == picked {
+			outcome = cmd.OutcomeAccepted
+		}
+		record := cmd.GenerationRecord{
+			Time:      time.Now(),
+			Repo:      cmd.CurrentRepo(),
+			Model:     result.model,
+			LatencyMS: result.latency.Milliseconds(),
+			DiffBytes: len(diff),
+			DiffHash:  cmd.DiffHash(diff),
+			Outcome:   outcome,
+			Message:   result.message,
+		}
+		if err := cmd.RecordGeneration(record); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record the generation: %v\n", err)
+		}
+	}
+}
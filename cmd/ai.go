@@ -2,106 +2,322 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
+	"time"
 )
 
-// OllamaRequest represents a request to the Ollama API
-type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+// ErrEmptyResponse is returned when the API response contains no generated text
+var ErrEmptyResponse = errors.New("no generated text found in the API response")
+
+// Options holds the model parameters sent with a request. Unset fields are
+// omitted so the server's defaults apply.
+type Options struct {
+	Seed        *int     `json:"seed,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	MaxTokens   *int     `json:"num_predict,omitempty"` // Most tokens to generate
+}
+
+// Validate returns an error describing every parameter out of range
+func (o Options) Validate() error {
+	return o.validate("")
+}
+
+// validate is Validate with the parameter names prefixed, e.g. with the
+// modelDefaults entry they come from
+func (o Options) validate(prefix string) error {
+	var errs []error
+	if o.Temperature != nil && *o.Temperature < 0 {
+		errs = append(errs, fmt.Errorf("%stemperature can't be negative", prefix))
+	}
+	if o.TopP != nil && (*o.TopP < 0 || *o.TopP > 1) {
+		errs = append(errs, fmt.Errorf("%stop_p must be between 0 and 1", prefix))
+	}
+	if o.MaxTokens != nil && *o.MaxTokens <= 0 {
+		errs = append(errs, fmt.Errorf("%snum_predict must be positive", prefix))
+	}
+	return errors.Join(errs...)
+}
+
+// MergeOptions returns base with every field set in override replacing it.
+// It returns nil if neither sets anything.
+func MergeOptions(base, override *Options) *Options {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	var merged Options
+	if base != nil {
+		merged = *base
+	}
+	if override != nil {
+		if override.Seed != nil {
+			merged.Seed = override.Seed
+		}
+		if override.Temperature != nil {
+			merged.Temperature = override.Temperature
+		}
+		if override.TopP != nil {
+			merged.TopP = override.TopP
+		}
+		if override.Stop != nil {
+			merged.Stop = override.Stop
+		}
+		if override.MaxTokens != nil {
+			merged.MaxTokens = override.MaxTokens
+		}
+	}
+	return &merged
+}
+
+// DeterministicOptions returns options that make generation reproducible for
+// the same diff, model and server version
+func DeterministicOptions() *Options {
+	seed := 42
+	temperature := 0.0
+	topP := 1.0
+	return &Options{
+		Seed:        &seed,
+		Temperature: &temperature,
+		TopP:        &topP,
+	}
+}
+
+// StatusError is returned when the model API responds with a non-OK status
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // Delay asked for by the Retry-After header, 0 if there was none
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned non-OK status: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// contextLengthPhrases are fragments of the errors servers return when the prompt doesn't fit the context window
+var contextLengthPhrases = []string{
+	"context length",
+	"context_length",
+	"context window",
+	"maximum context",
+	"too many tokens",
+	"prompt is too long",
+}
+
+// IsContextLengthError reports whether err is the API rejecting a prompt that is too long for the model
+func IsContextLengthError(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	body := strings.ToLower(statusErr.Body)
+	for _, phrase := range contextLengthPhrases {
+		if strings.Contains(body, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateCommitMessage generates a commit message with provider
+func (c *Client) GenerateCommitMessage(provider Provider, gitDiff, model, promptTemplate string, options *Options) (string, error) {
+	return c.GenerateCommitMessageContext(context.Background(), provider, gitDiff, model, promptTemplate, options)
+}
+
+// GenerateCommitMessageContext is like GenerateCommitMessage, giving up when ctx is done
+func (c *Client) GenerateCommitMessageContext(ctx context.Context, provider Provider, gitDiff, model, promptTemplate string, options *Options) (string, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(promptTemplate, gitDiff),
+		Options: options,
+	}
+
+	text, _, err := c.generateText(ctx, provider, prompt)
+	return text, err
 }
 
-// OllamaResponse represents a response from the Ollama API
-// The Ollama API might return the response in different formats
-// We'll handle multiple possible response structures
-type OllamaResponse struct {
-	Response string `json:"response"`
-	Content  string `json:"content"` // Some versions use content instead of response
+// GenerateReview asks the model to critique the changes using the review prompt template
+func (c *Client) GenerateReview(provider Provider, gitDiff, model, reviewTemplate string, options *Options) (string, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(reviewTemplate, gitDiff),
+		Options: options,
+	}
+	return c.sendPrompt(provider, prompt)
+}
+
+// GenerateExplanation asks the model for a plain-English walkthrough of the
+// changes using the explain prompt template
+func (c *Client) GenerateExplanation(provider Provider, gitDiff, model, explainTemplate string, options *Options) (string, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(explainTemplate, gitDiff),
+		Options: options,
+	}
+	return c.sendPrompt(provider, prompt)
+}
+
+// summaryPromptTemplate asks the model to describe a large diff, as the first
+// of two stages
+const summaryPromptTemplate = `Summarize the following changes for someone who will write the commit message.
+Describe what changed and why it appears to have changed, grouped by area, in at most 15 short lines.
+Mention new, removed and renamed files. Respond ONLY with the summary.
+
+Changes:
+%s`
+
+// SummarizeChanges asks the model for a short description of the changes,
+// which then replaces the diff in the commit message prompt
+func (c *Client) SummarizeChanges(provider Provider, gitDiff, model string, options *Options) (string, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(summaryPromptTemplate, gitDiff),
+		Options: options,
+	}
+	return c.sendPrompt(provider, prompt)
+}
+
+// refinePromptTemplate asks the model to revise a commit message following an instruction
+const refinePromptTemplate = `Here is a git commit message for the changes below:
+
+%s
+
+Rewrite the commit message following this instruction: %s
+
+Respond ONLY with the revised commit message, no other text, explanation, or quotes.
+
+Changes:
+%s`
+
+// followUpPromptTemplate revises the previous answer when the model still has
+// the conversation, so neither the message nor the diff needs to be sent again
+const followUpPromptTemplate = `Rewrite your previous commit message following this instruction: %s
+
+Respond ONLY with the revised commit message, no other text, explanation, or quotes.`
+
+// RefineCommitMessage asks the model to revise message according to the
+// user's instruction. If conversation holds the context returned by the
+// previous refinement, only the instruction is sent. It returns the revised
+// message and the context to pass to the next refinement, which is empty if
+// the server doesn't return one.
+func (c *Client) RefineCommitMessage(provider Provider, gitDiff, message, instruction, model string, options *Options, conversation []int) (string, []int, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(refinePromptTemplate, message, instruction, gitDiff),
+		Options: options,
+	}
+	if len(conversation) > 0 {
+		prompt.Text = fmt.Sprintf(followUpPromptTemplate, instruction)
+		prompt.Context = conversation
+	}
+	return c.generateText(context.Background(), provider, prompt)
 }
 
-// GenerateCommitMessage generates a commit message using the Ollama API
-func GenerateCommitMessage(gitDiff, model, apiURL, promptTemplate string) (string, error) {
-	// Prepare prompt for Ollama
-	prompt := fmt.Sprintf(promptTemplate, gitDiff)
+// subjectPromptTemplate asks the model for a new subject line matching an existing body
+const subjectPromptTemplate = `Here is the body of a git commit message for the changes below:
 
-	// Prepare request to Ollama API
-	ollamaReq := OllamaRequest{
-		Model:  model,
-		Prompt: prompt,
-		Stream: false, // We want the complete response, not streamed
+%s
+
+Write a better subject line for this commit. Use imperative mood and keep it under 50 characters.
+
+Respond ONLY with the subject line, no other text, explanation, or quotes.
+
+Changes:
+%s`
+
+// RegenerateSubject asks the model for a new subject line for the commit
+// with the given body, leaving the body itself untouched
+func (c *Client) RegenerateSubject(provider Provider, gitDiff, body, model string, options *Options) (string, error) {
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(subjectPromptTemplate, body, gitDiff),
+		Options: options,
+	}
+	response, err := c.sendPrompt(provider, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	// Only keep the first line in case the model added more
+	subject, _ := SplitMessage(response, SeparatorNewline)
+	if subject == "" {
+		return "", fmt.Errorf("model returned an empty subject")
 	}
+	return subject, nil
+}
+
+// postRequest sends the request body to the API with the given extra headers
+// and returns the response body
+func (c *Client) postRequest(ctx context.Context, apiURL string, reqBody []byte, header http.Header) ([]byte, error) {
+	release := c.acquireSlot()
+	defer release()
+	c.waitForRateLimit()
 
-	reqBody, err := json.Marshal(ollamaReq)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
 	}
 
-	// Send request to Ollama API
-	resp, err := http.Post(apiURL, "application/json", bytes.NewBuffer(reqBody))
+	resp, err := c.http.Do(req)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to call Ollama API: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrAPIUnreachable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Ollama API returned non-OK status: %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	// Read the full response body
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	// For debugging
-	// fmt.Printf("Raw API Response: %s\n", string(bodyBytes))
-
-	// Parse response
-	var ollamaResp OllamaResponse
-	if err := json.Unmarshal(bodyBytes, &ollamaResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
-	}
-
-	// Check which field has the content
-	var commitMsg string
-	if ollamaResp.Response != "" {
-		commitMsg = strings.TrimSpace(ollamaResp.Response)
-	} else if ollamaResp.Content != "" {
-		commitMsg = strings.TrimSpace(ollamaResp.Content)
-	} else {
-		// Try to find any relevant text in the response
-		if strings.Contains(string(bodyBytes), "response") || strings.Contains(string(bodyBytes), "content") {
-			// Try to extract the value manually
-			for _, line := range strings.Split(string(bodyBytes), ",") {
-				if strings.Contains(line, "response") || strings.Contains(line, "content") {
-					parts := strings.SplitN(line, ":", 2)
-					if len(parts) > 1 {
-						commitMsg = strings.TrimSpace(parts[1])
-						// Remove quotes
-						commitMsg = strings.Trim(commitMsg, "\"' ")
-						break
-					}
-				}
-			}
-		}
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
 
-		// If still empty, use the entire response as a fallback
-		if commitMsg == "" {
-			commitMsg = strings.TrimSpace(string(bodyBytes))
-		}
+	// A misconfigured URL often points at a web UI instead of the API
+	if err := checkJSONResponse(resp.Header.Get("Content-Type"), bodyBytes); err != nil {
+		return nil, err
 	}
+	return bodyBytes, nil
+}
 
-	// Remove quotes if they're wrapping the message
-	if (strings.HasPrefix(commitMsg, "\"") && strings.HasSuffix(commitMsg, "\"")) ||
-		(strings.HasPrefix(commitMsg, "'") && strings.HasSuffix(commitMsg, "'")) {
-		commitMsg = commitMsg[1 : len(commitMsg)-1]
+// checkJSONResponse returns an error if the response is neither labelled nor
+// shaped like JSON. The body is trusted over the header since some servers
+// mislabel their JSON responses.
+func checkJSONResponse(contentType string, body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return nil
+	}
+	if strings.Contains(contentType, "json") {
+		return nil
 	}
+	return notJSONError(contentType)
+}
 
-	return commitMsg, nil
+// notJSONError describes a response that isn't JSON
+func notJSONError(contentType string) error {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	if contentType == "" {
+		contentType = "a response without a content type"
+	}
+	return fmt.Errorf("expected JSON from the API but got %s; check the URL", contentType)
 }
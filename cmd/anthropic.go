@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	anthropicMessagesPath  = "/v1/messages"
+	anthropicVersion       = "2023-06-01" // Version of the Messages API the requests follow
+	anthropicDefaultTokens = 1024         // max_tokens is required, so this applies unless num_predict is set
+)
+
+func init() {
+	RegisterProvider("anthropic", newAnthropicProvider, func(apiURL *url.URL) bool {
+		return apiURL.Hostname() == "api.anthropic.com" ||
+			strings.HasSuffix(strings.TrimSuffix(apiURL.Path, "/"), anthropicMessagesPath)
+	})
+}
+
+// anthropicProvider generates text with Anthropic's Messages API
+type anthropicProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newAnthropicProvider returns an Anthropic provider. A base URL like
+// https://api.anthropic.com is completed with /v1/messages. The API key is
+// read from OLLAMA_COMMIT_API_KEY, ANTHROPIC_API_KEY or apiKeys.anthropic.
+func newAnthropicProvider(client *Client, apiURL string) Provider {
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), anthropicMessagesPath) {
+		apiURL = strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/v1") + anthropicMessagesPath
+	}
+	return anthropicProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("anthropic", "ANTHROPIC_API_KEY", apiURL, "api.anthropic.com")}
+}
+
+// anthropicRequest is a Messages API request
+type anthropicRequest struct {
+	Model         string        `json:"model"`
+	MaxTokens     int           `json:"max_tokens"`
+	Messages      []chatMessage `json:"messages"`
+	Temperature   *float64      `json:"temperature,omitempty"`
+	TopP          *float64      `json:"top_p,omitempty"`
+	StopSequences []string      `json:"stop_sequences,omitempty"`
+}
+
+// anthropicResponse is a Messages API response
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (p anthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// Generate sends the prompt as a single user message. The API has no seed
+// or JSON mode, so those are left to the prompt.
+func (p anthropicProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the anthropic provider needs an API key; set ANTHROPIC_API_KEY or apiKeys.anthropic")
+	}
+
+	req := anthropicRequest{
+		Model:     prompt.Model,
+		MaxTokens: anthropicDefaultTokens,
+		Messages:  []chatMessage{{Role: "user", Content: prompt.Text}},
+	}
+	if prompt.Options != nil {
+		if prompt.Options.MaxTokens != nil {
+			req.MaxTokens = *prompt.Options.MaxTokens
+		}
+		req.Temperature = prompt.Options.Temperature
+		req.TopP = prompt.Options.TopP
+		req.StopSequences = prompt.Options.Stop
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", p.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+	bodyBytes, err := p.client.postRequest(ctx, p.apiURL, reqBody, header)
+	if err != nil {
+		return "", err
+	}
+
+	var resp anthropicResponse
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	p.client.reportUsage(Usage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens})
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return "", fmt.Errorf("%w; check the model", ErrEmptyResponse)
+	}
+	return text.String(), nil
+}
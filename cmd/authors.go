@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveCoAuthor expands an alias from the author map into a "Name <email>"
+// identity. Identities that already contain an email are used as given.
+// Unknown aliases are passed through unless strict is set.
+func ResolveCoAuthor(alias string, authorMap map[string]string, strict bool) (string, error) {
+	alias = strings.TrimSpace(alias)
+	if identity, ok := authorMap[alias]; ok {
+		return identity, nil
+	}
+	if strings.Contains(alias, "<") {
+		return alias, nil
+	}
+	if strict {
+		return "", fmt.Errorf("unknown co-author alias %q", alias)
+	}
+	return alias, nil
+}
+
+// AddCoAuthors appends a Co-authored-by trailer for each identity not already in the message
+func AddCoAuthors(message string, identities []string) string {
+	for _, identity := range identities {
+		trailer := "Co-authored-by: " + identity
+		if !strings.Contains(message, trailer) {
+			message = AppendFooter(message, trailer)
+		}
+	}
+	return message
+}
@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// awsCredentials are the credentials requests to AWS are signed with
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time // Zero for credentials that don't expire
+}
+
+// awsCredentialsCache holds credentials fetched from a container or instance
+// role until shortly before they expire
+var awsCredentialsCache struct {
+	sync.Mutex
+	credentials awsCredentials
+}
+
+// awsMetadataClient fetches role credentials. It bypasses allowedHosts, which
+// guards where the changes are sent rather than these local endpoints.
+var awsMetadataClient = &http.Client{Timeout: 2 * time.Second}
+
+// awsProfile returns the profile of the shared AWS files in use
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// awsSharedFile returns the path of a shared AWS file, set by variable or
+// under ~/.aws
+func awsSharedFile(variable, name string) string {
+	if path := os.Getenv(variable); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readAWSProfile returns the settings of a profile in a shared AWS file, nil
+// if the file or the profile doesn't exist. Sections of the config file are
+// named "profile <name>", except for the default profile.
+func readAWSProfile(path, profile string, configFile bool) map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	section := profile
+	if configFile && profile != "default" {
+		section = "profile " + profile
+	}
+
+	var settings map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			if inSection && settings == nil {
+				settings = map[string]string{}
+			}
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inSection {
+			settings[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
+
+// AWSRegion returns the region from AWS_REGION, AWS_DEFAULT_REGION or the
+// shared config file, empty if none sets one
+func AWSRegion() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	if region := os.Getenv("AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return readAWSProfile(awsSharedFile("AWS_CONFIG_FILE", "config"), awsProfile(), true)["region"]
+}
+
+// resolveAWSCredentials finds credentials the way the AWS SDKs do: the
+// environment, then the shared credentials and config files, then the role
+// of the container or EC2 instance
+func resolveAWSCredentials(ctx context.Context) (awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	profile := awsProfile()
+	for _, settings := range []map[string]string{
+		readAWSProfile(awsSharedFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile, false),
+		readAWSProfile(awsSharedFile("AWS_CONFIG_FILE", "config"), profile, true),
+	} {
+		if settings["aws_access_key_id"] != "" && settings["aws_secret_access_key"] != "" {
+			return awsCredentials{
+				AccessKeyID:     settings["aws_access_key_id"],
+				SecretAccessKey: settings["aws_secret_access_key"],
+				SessionToken:    settings["aws_session_token"],
+			}, nil
+		}
+	}
+
+	awsCredentialsCache.Lock()
+	defer awsCredentialsCache.Unlock()
+	if cached := awsCredentialsCache.credentials; cached.AccessKeyID != "" && time.Until(cached.Expiration) > time.Minute {
+		return cached, nil
+	}
+	credentials, err := fetchRoleCredentials(ctx)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials found in the environment, the shared files (profile %q) or an instance role: %w", profile, err)
+	}
+	awsCredentialsCache.credentials = credentials
+	return credentials, nil
+}
+
+// roleCredentials is the credentials document of a container or instance role
+type roleCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// fetchRoleCredentials fetches the credentials of the ECS task role, or else
+// of the EC2 instance profile using IMDSv2
+func fetchRoleCredentials(ctx context.Context) (awsCredentials, error) {
+	header := http.Header{}
+	var endpoint string
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = "http://169.254.170.2" + uri
+	} else if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); uri != "" {
+		if !isContainerCredentialsURL(uri) {
+			return awsCredentials{}, fmt.Errorf("AWS_CONTAINER_CREDENTIALS_FULL_URI must point at this machine or the ECS or EKS credentials endpoint, not %s", uri)
+		}
+		endpoint = uri
+		if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+			header.Set("Authorization", token)
+		}
+	} else {
+		const imds = "http://169.254.169.254/latest"
+		token, err := metadataRequest(ctx, http.MethodPut, imds+"/api/token", http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"21600"}})
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+		roles, err := metadataRequest(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/", header)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+		if role == "" {
+			return awsCredentials{}, fmt.Errorf("the instance has no IAM role")
+		}
+		endpoint = imds + "/meta-data/iam/security-credentials/" + url.PathEscape(role)
+	}
+
+	body, err := metadataRequest(ctx, http.MethodGet, endpoint, header)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	var role roleCredentials
+	if err := json.Unmarshal(body, &role); err != nil {
+		return awsCredentials{}, fmt.Errorf("failed to parse the role credentials: %w", err)
+	}
+	if role.AccessKeyID == "" || role.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("the role credentials are incomplete")
+	}
+	return awsCredentials{AccessKeyID: role.AccessKeyID, SecretAccessKey: role.SecretAccessKey, SessionToken: role.Token, Expiration: role.Expiration}, nil
+}
+
+// containerCredentialsIPs are the addresses of the ECS and EKS container
+// credentials endpoints
+var containerCredentialsIPs = []net.IP{net.ParseIP("169.254.170.2"), net.ParseIP("169.254.170.23"), net.ParseIP("fd00:ec2::23")}
+
+// isContainerCredentialsURL reports whether a full container credentials URI
+// is local or one of the container credentials endpoints, so the
+// authorization token isn't sent elsewhere
+func isContainerCredentialsURL(uri string) bool {
+	if IsLocalURL(uri) {
+		return true
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(parsed.Hostname())
+	return ip != nil && slices.ContainsFunc(containerCredentialsIPs, ip.Equal)
+}
+
+// metadataRequest makes a request to a credentials endpoint and returns the body
+func metadataRequest(ctx context.Context, method, endpoint string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+	resp, err := awsMetadataClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// signAWSRequest returns the headers signing a POST of body to endpoint with
+// Signature Version 4
+func signAWSRequest(credentials awsCredentials, service, region, endpoint string, body []byte, now time.Time) (http.Header, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	header := http.Header{}
+	header.Set("X-Amz-Date", amzDate)
+	signed := map[string]string{"host": parsed.Host, "x-amz-date": amzDate}
+	if credentials.SessionToken != "" {
+		header.Set("X-Amz-Security-Token", credentials.SessionToken)
+		signed["x-amz-security-token"] = credentials.SessionToken
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signed[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Services other than S3 sign the path with each segment encoded again
+	segments := strings.Split(parsed.EscapedPath(), "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		strings.Join(segments, "/"),
+		parsed.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+	return header, nil
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, as
+// Signature Version 4 requires
+func awsURIEncode(s string) string {
+	var encoded strings.Builder
+	for _, c := range []byte(s) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignAWSRequest checks the signature against the post-vanilla request of
+// the AWS Signature Version 4 test suite
+func TestSignAWSRequest(t *testing.T) {
+	credentials := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	header, err := signAWSRequest(credentials, "service", "us-east-1", "https://example.amazonaws.com/", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"
+	if got := header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %q, want %q", got, want)
+	}
+	if got := header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q, want 20150830T123600Z", got)
+	}
+}
+
+func TestContainerCredentialsURL(t *testing.T) {
+	tests := map[string]bool{
+		"http://localhost:8080/creds":             true,
+		"http://127.0.0.1/creds":                  true,
+		"http://[::1]/creds":                      true,
+		"http://169.254.170.2/v2/credentials":     true,
+		"http://169.254.170.23/v1/credentials":    true,
+		"http://[fd00:ec2::23]/v1/credentials":    true,
+		"https://evil.example/creds":              false,
+		"http://169.254.170.3/v2/credentials":     false,
+		"http://169.254.170.2.evil.example/creds": false,
+	}
+	for uri, want := range tests {
+		if got := isContainerCredentialsURL(uri); got != want {
+			t.Errorf("isContainerCredentialsURL(%q) = %v, want %v", uri, got, want)
+		}
+	}
+
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "https://evil.example/creds")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "secret")
+	if _, err := fetchRoleCredentials(context.Background()); err == nil || !strings.Contains(err.Error(), "AWS_CONTAINER_CREDENTIALS_FULL_URI") {
+		t.Errorf("fetchRoleCredentials() error = %v, want the URI refused", err)
+	}
+}
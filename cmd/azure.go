@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is configured
+const DefaultAzureAPIVersion = "2024-10-21"
+
+func init() {
+	RegisterProvider("azure", newAzureProvider, func(apiURL *url.URL) bool {
+		host := apiURL.Hostname()
+		return strings.HasSuffix(host, ".openai.azure.com") || strings.HasSuffix(host, ".cognitiveservices.azure.com")
+	})
+}
+
+// SetAzureDeployment sets the deployment requests of the azure provider are
+// routed to, the model of each request if empty, and the API version, the
+// default if empty
+func (c *Client) SetAzureDeployment(deployment, apiVersion string) {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+	c.azureDeployment = deployment
+	c.azureAPIVersion = apiVersion
+}
+
+// azureProvider generates text with an Azure OpenAI chat completions deployment
+type azureProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newAzureProvider returns an Azure OpenAI provider. The URL is either the
+// resource endpoint, like https://example.openai.azure.com, or the full URL
+// of a deployment. The API key is read from OLLAMA_COMMIT_API_KEY,
+// AZURE_OPENAI_API_KEY or apiKeys.azure.
+func newAzureProvider(client *Client, apiURL string) Provider {
+	return azureProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("azure", "AZURE_OPENAI_API_KEY", apiURL, ".openai.azure.com", ".cognitiveservices.azure.com")}
+}
+
+// deploymentURL returns the chat completions URL of the deployment serving model
+func (p azureProvider) deploymentURL(model string) (string, error) {
+	parsed, err := url.Parse(p.apiURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid Azure endpoint %q: %w", p.apiURL, err)
+	}
+	if !strings.Contains(parsed.Path, "/openai/deployments/") {
+		deployment := p.client.azureDeployment
+		if deployment == "" {
+			deployment = model
+		}
+		parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/openai/deployments/" + url.PathEscape(deployment) + chatCompletionsPath
+	}
+	if query := parsed.Query(); query.Get("api-version") == "" {
+		query.Set("api-version", p.client.azureAPIVersion)
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String(), nil
+}
+
+func (p azureProvider) Name() string {
+	return "azure"
+}
+
+func (p azureProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the azure provider needs an API key; set AZURE_OPENAI_API_KEY or apiKeys.azure")
+	}
+	apiURL, err := p.deploymentURL(prompt.Model)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	header.Set("api-key", p.apiKey)
+	return p.client.sendChatRequest(ctx, apiURL, header, prompt)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// BatchResult is the outcome of running the tool in one repository of a batch
+type BatchResult struct {
+	Repo string
+	Err  error
+}
+
+// ReadRepoList reads repository paths from a file, one per line. Blank lines
+// and lines starting with # are skipped, and relative paths are resolved
+// against the list file's directory.
+func ReadRepoList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repo list: %v", err)
+	}
+	defer file.Close()
+
+	var repos []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		repos = append(repos, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repo list: %v", err)
+	}
+	return repos, nil
+}
+
+// RunBatch runs this executable with args in each repository, so every repo
+// resolves its own configuration, and continues past failures
+func RunBatch(repos []string, args []string) []BatchResult {
+	self, err := os.Executable()
+	if err != nil {
+		self = os.Args[0]
+	}
+
+	results := make([]BatchResult, 0, len(repos))
+	for _, repo := range repos {
+		fmt.Printf("==> %s\n", repo)
+
+		cmd := exec.Command(self, args...)
+		cmd.Dir = repo
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		results = append(results, BatchResult{Repo: repo, Err: cmd.Run()})
+		fmt.Println()
+	}
+	return results
+}
+
+// WithoutFlag returns args with every occurrence of the named flag and its value removed
+func WithoutFlag(args []string, name string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(kept, args[i:]...)
+		}
+
+		trimmed := strings.TrimLeft(arg, "-")
+		if trimmed == name && arg != trimmed {
+			i++ // Skip the value too
+			continue
+		}
+		if strings.HasPrefix(trimmed, name+"=") && arg != trimmed {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// bedrockAnthropicVersion is the Messages API version Claude models on Bedrock take
+const bedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// awsRegionPattern matches AWS region names like us-east-1 or us-gov-west-1,
+// so a region can't change the host of the endpoint it is part of
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+func init() {
+	RegisterProvider("bedrock", newBedrockProvider, func(apiURL *url.URL) bool {
+		host := apiURL.Hostname()
+		return strings.HasPrefix(host, "bedrock-runtime.") && strings.HasSuffix(host, ".amazonaws.com")
+	})
+}
+
+// SetBedrockRegion sets the region of the bedrock provider's endpoint
+func (c *Client) SetBedrockRegion(region string) {
+	c.bedrockRegion = region
+}
+
+// bedrockProvider generates text with the Bedrock Runtime InvokeModel API,
+// signing requests with the AWS credentials of the environment
+type bedrockProvider struct {
+	client *Client
+	apiURL string
+}
+
+// newBedrockProvider returns a Bedrock provider. Requests go to the
+// bedrock-runtime endpoint of the configured region unless the API URL is
+// changed from its default, e.g. to a VPC endpoint.
+func newBedrockProvider(client *Client, apiURL string) Provider {
+	if apiURL == DefaultAPIURL {
+		apiURL = ""
+	}
+	return bedrockProvider{client: client, apiURL: strings.TrimSuffix(apiURL, "/")}
+}
+
+// region returns the region requests are signed for: the configured one,
+// the one of a bedrock-runtime URL, or the region of the AWS environment
+func (p bedrockProvider) region() string {
+	if p.client.bedrockRegion != "" {
+		return p.client.bedrockRegion
+	}
+	if parsed, err := url.Parse(p.apiURL); err == nil {
+		if parts := strings.Split(parsed.Hostname(), "."); len(parts) == 4 && parts[0] == "bedrock-runtime" {
+			return parts[1]
+		}
+	}
+	return AWSRegion()
+}
+
+// baseURL returns the endpoint requests are sent to
+func (p bedrockProvider) baseURL(region string) string {
+	if p.apiURL != "" {
+		return p.apiURL
+	}
+	return "https://bedrock-runtime." + region + ".amazonaws.com"
+}
+
+// invokeURL returns the InvokeModel URL of a model. Model IDs like
+// anthropic.claude-3-haiku-20240307-v1:0 are escaped, colon included.
+func (p bedrockProvider) invokeURL(region, model string) string {
+	return p.baseURL(region) + "/model/" + strings.ReplaceAll(url.PathEscape(model), ":", "%3A") + "/invoke"
+}
+
+// bedrockFamily returns the model family of a model ID, skipping the
+// geography prefix of cross-region inference profiles like us.anthropic.…
+func bedrockFamily(model string) string {
+	for _, family := range []string{"anthropic", "meta"} {
+		if strings.HasPrefix(model, family+".") || strings.Contains(model, "."+family+".") {
+			return family
+		}
+	}
+	return ""
+}
+
+// bedrockClaudeRequest is the InvokeModel body of Claude models
+type bedrockClaudeRequest struct {
+	AnthropicVersion string        `json:"anthropic_version"`
+	MaxTokens        int           `json:"max_tokens"`
+	Messages         []chatMessage `json:"messages"`
+	Temperature      *float64      `json:"temperature,omitempty"`
+	TopP             *float64      `json:"top_p,omitempty"`
+	StopSequences    []string      `json:"stop_sequences,omitempty"`
+}
+
+// bedrockLlamaRequest is the InvokeModel body of Llama models
+type bedrockLlamaRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxGenLen   *int     `json:"max_gen_len,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+// bedrockLlamaResponse is the InvokeModel response of Llama models
+type bedrockLlamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int    `json:"prompt_token_count"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+}
+
+func (p bedrockProvider) endpoint() string {
+	return p.baseURL(p.region())
+}
+
+func (p bedrockProvider) Name() string {
+	return "bedrock"
+}
+
+// Generate invokes the model with the body of its family. Claude models get
+// the Messages API and Llama models a prompt in the Llama 3 chat format.
+func (p bedrockProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	family := bedrockFamily(prompt.Model)
+	if family == "" {
+		return "", fmt.Errorf("unsupported Bedrock model %q; use a Claude (anthropic.…) or Llama (meta.…) model", prompt.Model)
+	}
+	region := p.region()
+	if region == "" {
+		return "", fmt.Errorf("no AWS region configured; set bedrockRegion or AWS_REGION")
+	}
+	if !awsRegionPattern.MatchString(region) {
+		return "", fmt.Errorf("%q is not an AWS region like us-east-1", region)
+	}
+	if endpoint := p.baseURL(region); p.client.untrustedURLs && !onHost(endpoint, []string{".amazonaws.com"}) {
+		return "", fmt.Errorf("not signing requests to %s with the AWS credentials, since the URL doesn't come from your own configuration", endpoint)
+	}
+	credentials, err := resolveAWSCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	options := prompt.Options
+	if options == nil {
+		options = &Options{}
+	}
+	var req any
+	switch family {
+	case "anthropic":
+		claude := bedrockClaudeRequest{
+			AnthropicVersion: bedrockAnthropicVersion,
+			MaxTokens:        anthropicDefaultTokens,
+			Messages:         []chatMessage{{Role: "user", Content: prompt.Text}},
+			Temperature:      options.Temperature,
+			TopP:             options.TopP,
+			StopSequences:    options.Stop,
+		}
+		if options.MaxTokens != nil {
+			claude.MaxTokens = *options.MaxTokens
+		}
+		req = claude
+	case "meta":
+		req = bedrockLlamaRequest{
+			Prompt:      "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\n" + prompt.Text + "<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+			MaxGenLen:   options.MaxTokens,
+			Temperature: options.Temperature,
+			TopP:        options.TopP,
+		}
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := p.invokeURL(region, prompt.Model)
+	header, err := signAWSRequest(credentials, "bedrock", region, endpoint, reqBody, time.Now())
+	if err != nil {
+		return "", err
+	}
+	bodyBytes, err := p.client.postRequest(ctx, endpoint, reqBody, header)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	var usage Usage
+	switch family {
+	case "anthropic":
+		var resp anthropicResponse
+		if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+		for _, block := range resp.Content {
+			if block.Type == "text" {
+				text += block.Text
+			}
+		}
+		usage = Usage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens}
+	case "meta":
+		var resp bedrockLlamaResponse
+		if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+		text = resp.Generation
+		usage = Usage{PromptTokens: resp.PromptTokenCount, CompletionTokens: resp.GenerationTokenCount}
+	}
+	p.client.reportUsage(usage)
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w; check the model", ErrEmptyResponse)
+	}
+	return text, nil
+}
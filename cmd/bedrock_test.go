@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBedrockRejectsInvalidRegion(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	for _, region := range []string{"x.evil.example#", "us-east-1.evil.example/", "US-EAST-1", "useast1"} {
+		client := NewClient()
+		client.SetBedrockRegion(region)
+		provider, err := NewProvider(client, "bedrock", DefaultAPIURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = provider.Generate(context.Background(), Prompt{Model: "anthropic.claude-3-haiku-20240307-v1:0", Text: "diff"})
+		if err == nil || !strings.Contains(err.Error(), "not an AWS region") {
+			t.Errorf("Generate() with region %q error = %v, want the region rejected", region, err)
+		}
+	}
+
+	for _, region := range []string{"us-east-1", "eu-central-2", "us-gov-west-1", "ap-southeast-4"} {
+		if !awsRegionPattern.MatchString(region) {
+			t.Errorf("region %q rejected", region)
+		}
+	}
+}
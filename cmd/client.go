@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Client sends requests to model APIs. Each client has its own settings,
+// rate limit and connections, so clients configured differently can be used
+// side by side. Create one with NewClient.
+type Client struct {
+	http              *http.Client
+	allowedHosts      []string          // Hosts requests may be sent to, any host if empty
+	retries           int               // Times a failed request is retried
+	retryOnRateLimit  bool              // Retry requests rejected with 429 Too Many Requests
+	limiter           *rateLimiter      // Spaces out requests when a rate limit is configured
+	inFlight          chan struct{}     // Bounds the requests sent at the same time when a limit is configured
+	idleTimeout       time.Duration     // Longest gap allowed between streamed tokens; zero disables streaming
+	firstTokenTimeout time.Duration     // Longest wait for the first streamed token; zero means no limit
+	responseFields    []string          // Field names searched for the generated text
+	keys              map[string]string // API keys from the configuration by provider
+	untrustedURLs     bool              // Only give API keys to providers sending requests to their own hosts
+	azureDeployment   string            // Deployment of the azure provider, the model of each request if empty
+	azureAPIVersion   string            // api-version of the azure provider
+	bedrockRegion     string            // Region of the bedrock provider, resolved like the AWS SDKs do if empty
+
+	OnRetry func(delay time.Duration, err error) // If set, called before waiting to retry a failed request
+	OnUsage func(Usage)                          // If set, called with the token usage of every response that reports it
+	OnStall func(idle time.Duration)             // If set, called when a stalled stream is cut short and its partial output used
+}
+
+// NewClient returns a client with the default settings: no timeouts, no
+// retries, no limits and any host allowed
+func NewClient() *Client {
+	c := &Client{
+		responseFields:  DefaultResponseFields,
+		keys:            map[string]string{},
+		azureAPIVersion: DefaultAzureAPIVersion,
+	}
+	c.http = &http.Client{Transport: hostCheckTransport{http.DefaultTransport, c}}
+	return c
+}
+
+// SetTimeouts sets the timeouts of API requests. The connect timeout only
+// bounds establishing the connection, so an unreachable server fails fast
+// while slow models still get the full timeout to generate. A zero duration
+// means no limit.
+func (c *Client) SetTimeouts(connectTimeout, timeout time.Duration) {
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	c.http = &http.Client{
+		Transport: hostCheckTransport{transport, c},
+		Timeout:   timeout,
+	}
+}
+
+// reportUsage passes the token usage of a response to OnUsage, unless the
+// response reported none
+func (c *Client) reportUsage(usage Usage) {
+	if c.OnUsage != nil && (usage.PromptTokens > 0 || usage.CompletionTokens > 0) {
+		c.OnUsage(usage)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// compareColumnWidth is the width of each column in a side by side comparison
+const compareColumnWidth = 50
+
+// SideBySide lays out two texts in columns under their headers, wrapping
+// lines longer than the column width
+func SideBySide(leftHeader, left, rightHeader, right string) string {
+	leftLines := append([]string{leftHeader, strings.Repeat("-", compareColumnWidth)}, wrapLines(left, compareColumnWidth)...)
+	rightLines := append([]string{rightHeader, strings.Repeat("-", compareColumnWidth)}, wrapLines(right, compareColumnWidth)...)
+
+	var b strings.Builder
+	for i := 0; i < len(leftLines) || i < len(rightLines); i++ {
+		var l, r string
+		if i < len(leftLines) {
+			l = leftLines[i]
+		}
+		if i < len(rightLines) {
+			r = rightLines[i]
+		}
+		line := l + strings.Repeat(" ", compareColumnWidth-utf8.RuneCountInString(l)) + " | " + r
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// wrapLines splits text into lines of at most width characters, breaking at
+// spaces where possible
+func wrapLines(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			cut := width
+			for i := width; i > 0; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, string(runes[:cut]))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configField returns the Config field with the given JSON key
+func configField(key string) (reflect.StructField, bool) {
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == key && name != "" {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// ConfigKeys returns the keys of the settings a config file can hold, sorted
+func ConfigKeys() []string {
+	var keys []string
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name, _, _ := strings.Cut(configType.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unknownKeyError reports a key that isn't a setting
+func unknownKeyError(key string) error {
+	return fmt.Errorf("unknown setting %q; run 'ollama-commit config list' to see them", key)
+}
+
+// redactKey hides an API key, keeping its last characters so keys can be told apart
+func redactKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "****" + key[len(key)-4:]
+}
+
+// ConfigValue returns the effective value of a setting as JSON. API keys are
+// redacted.
+func ConfigValue(config Config, key string) (string, error) {
+	field, ok := configField(key)
+	if !ok {
+		return "", unknownKeyError(key)
+	}
+	if len(config.APIKeys) > 0 {
+		redacted := make(map[string]string, len(config.APIKeys))
+		for provider, apiKey := range config.APIKeys {
+			redacted[provider] = redactKey(apiKey)
+		}
+		config.APIKeys = redacted
+	}
+	data, err := json.Marshal(reflect.ValueOf(config).FieldByIndex(field.Index).Interface())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return string(data), nil
+}
+
+// parseConfigValue converts the text given on the command line to the JSON
+// value of the setting: strings are taken as is, numbers and booleans are
+// parsed, and lists and objects must be given as JSON
+func parseConfigValue(field reflect.StructField, text string) (json.RawMessage, error) {
+	var value any
+	switch field.Type.Kind() {
+	case reflect.String:
+		value = text
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", text)
+		}
+		value = b
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a whole number, got %q", text)
+		}
+		value = n
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", text)
+		}
+		value = f
+	default:
+		// Lists, maps and raw JSON like the schema
+		target := reflect.New(field.Type).Interface()
+		if err := json.Unmarshal([]byte(text), target); err != nil {
+			return nil, fmt.Errorf("expected JSON for %s, e.g. [\"a\", \"b\"] or {\"key\": \"value\"}: %w", field.Type, err)
+		}
+		return json.RawMessage(text), nil
+	}
+	return json.Marshal(value)
+}
+
+// EditableConfigPath returns the config file config set and unset change for
+// key: the file the configuration was loaded from, or ~/.ollama-commit.json
+// if there is none or only the home config can set key
+func EditableConfigPath(config Config, key string) (string, error) {
+	if config.Source() != "" && !slices.Contains(homeOnlySettings, key) {
+		return config.Source(), nil
+	}
+	return homeConfigPath()
+}
+
+// readConfigObject reads a config file as a JSON object, empty if the file doesn't exist
+func readConfigObject(path string) (map[string]json.RawMessage, error) {
+	settings := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// writeConfigObject validates the settings and writes them to path
+func writeConfigObject(path string, settings map[string]json.RawMessage) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(settings); err != nil {
+		return fmt.Errorf("failed to create config JSON: %w", err)
+	}
+	if err := parseConfig(path, buf.Bytes()).Validate(); err != nil {
+		return fmt.Errorf("the change would make the configuration invalid:\n%w", err)
+	}
+	if err := WriteFileAtomic(path, buf.Bytes(), configFileMode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// SetConfigValue sets one setting in the config file at path, leaving the
+// others as they are
+func SetConfigValue(path, key, text string) error {
+	field, ok := configField(key)
+	if !ok {
+		return unknownKeyError(key)
+	}
+	value, err := parseConfigValue(field, text)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	settings, err := readConfigObject(path)
+	if err != nil {
+		return err
+	}
+	settings[key] = value
+	return writeConfigObject(path, settings)
+}
+
+// UnsetConfigValue removes a setting from the config file at path so its
+// default applies again. It returns false if the file didn't set it.
+func UnsetConfigValue(path, key string) (bool, error) {
+	if _, ok := configField(key); !ok {
+		return false, unknownKeyError(key)
+	}
+
+	settings, err := readConfigObject(path)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := settings[key]; !ok {
+		return false, nil
+	}
+	delete(settings, key)
+	return true, writeConfigObject(path, settings)
+}
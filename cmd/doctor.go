@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Check is the outcome of one of the doctor's checks
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string // What was found
+	Fix    string // How to fix a failed check
+}
+
+// RunDoctor checks that the configuration, git, the repository, the API and
+// the model are usable, stopping at the first check the others depend on. The
+// API is reached with provider, if it can list the models.
+func RunDoctor(config Config, provider Provider) []Check {
+	var checks []Check
+
+	// The configuration
+	source := config.Source()
+	if source == "" {
+		source = "built-in defaults"
+	}
+	if err := config.Validate(); err != nil {
+		checks = append(checks, Check{
+			Name:   "Configuration",
+			Detail: fmt.Sprintf("%s is invalid:\n%v", source, err),
+			Fix:    "Correct the listed settings, or run 'ollama-commit config init' to create a new config file",
+		})
+	} else {
+		checks = append(checks, Check{Name: "Configuration", OK: true, Detail: source})
+	}
+
+	// Git and the repository
+	if _, err := exec.LookPath("git"); err != nil {
+		checks = append(checks, Check{
+			Name:   "Git",
+			Detail: "git was not found in PATH",
+			Fix:    "Install git (https://git-scm.com/downloads) and make sure it is in your PATH",
+		})
+	} else {
+		output, err := gitCommand("version").Output()
+		if err != nil {
+			checks = append(checks, Check{Name: "Git", Detail: fmt.Sprintf("git version failed: %v", err), Fix: "Reinstall git"})
+		} else {
+			checks = append(checks, Check{Name: "Git", OK: true, Detail: strings.TrimSpace(string(output))})
+			checks = append(checks, checkRepository())
+		}
+	}
+
+	// The API and the model
+	if !CanListModels(provider) {
+		return append(checks, Check{
+			Name:   "API",
+			OK:     true,
+			Detail: fmt.Sprintf("%s with the %s provider, which can't list its models, so neither the API nor %s were checked", ProviderURL(provider, config.OllamaAPIURL), provider.Name(), config.DefaultModel),
+		})
+	}
+	if err := CheckHostAllowed(ProviderURL(provider, config.OllamaAPIURL), config.AllowedHosts); err != nil {
+		return append(checks, Check{
+			Name:   "Ollama API",
+			Detail: err.Error(),
+			Fix:    "Add the host to allowedHosts or change ollamaApiUrl",
+		})
+	}
+	models, err := ListModels(provider)
+	if err != nil {
+		fix := "Start Ollama with 'ollama serve', or set ollamaApiUrl (or OLLAMA_COMMIT_URL) to the server's /api/generate URL"
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			fix = "The server answered but isn't an Ollama API; check that ollamaApiUrl points at Ollama's /api/generate"
+		}
+		return append(checks, Check{Name: "Ollama API", Detail: err.Error(), Fix: fix})
+	}
+	checks = append(checks, Check{Name: "Ollama API", OK: true, Detail: fmt.Sprintf("%s (%d models installed)", config.OllamaAPIURL, len(models))})
+
+	if !HasModel(models, config.DefaultModel) {
+		return append(checks, Check{
+			Name:   "Model",
+			Detail: fmt.Sprintf("%s is not installed on the server", config.DefaultModel),
+			Fix:    fmt.Sprintf("Run 'ollama pull %s', or pick an installed model with 'ollama-commit config init'", config.DefaultModel),
+		})
+	}
+	return append(checks, Check{Name: "Model", OK: true, Detail: config.DefaultModel})
+}
+
+// checkRepository checks that the working directory is in a repository git is willing to use
+func checkRepository() Check {
+	output, err := gitOutput("", "rev-parse", "--show-toplevel")
+	var ownership *OwnershipError
+	switch {
+	case err == nil:
+		return Check{Name: "Repository", OK: true, Detail: strings.TrimSpace(string(output))}
+	case errors.As(err, &ownership):
+		return Check{
+			Name:   "Repository",
+			Detail: "git refuses to use this repository because it is owned by another user",
+			Fix:    "Run 'git config --global --add safe.directory <path>' or use -safe-dir",
+		}
+	default:
+		return Check{
+			Name:   "Repository",
+			Detail: "the current directory is not in a git repository",
+			Fix:    "Change to a repository, or create one with 'git init'",
+		}
+	}
+}
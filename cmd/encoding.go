@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommitEncoding returns the repository's i18n.commitEncoding, or an empty string if unset
+func CommitEncoding() string {
+	return gitConfigValue("i18n.commitEncoding")
+}
+
+// isUTF8 reports whether the encoding name refers to UTF-8
+func isUTF8(encoding string) bool {
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		return true
+	}
+	return false
+}
+
+// EncodeMessage converts a UTF-8 message to the given encoding. Latin-1 is
+// handled directly, other encodings are converted with iconv.
+func EncodeMessage(message, encoding string) ([]byte, error) {
+	if isUTF8(encoding) {
+		return []byte(message), nil
+	}
+
+	switch strings.ToLower(encoding) {
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		encoded := make([]byte, 0, len(message))
+		for _, r := range message {
+			if r > 0xff {
+				return nil, fmt.Errorf("character %q cannot be encoded in %s", r, encoding)
+			}
+			encoded = append(encoded, byte(r))
+		}
+		return encoded, nil
+	}
+
+	// Leave everything else to iconv, which fails on characters it can't represent
+	cmdIconv := exec.Command("iconv", "-f", "UTF-8", "-t", encoding)
+	cmdIconv.Stdin = strings.NewReader(message)
+	var stderr bytes.Buffer
+	cmdIconv.Stderr = &stderr
+	output, err := cmdIconv.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to convert commit message to %s: %s", encoding, msg)
+		}
+		return nil, fmt.Errorf("failed to convert commit message to %s: %w", encoding, err)
+	}
+	return output, nil
+}
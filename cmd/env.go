@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// envPrefix starts the environment variables read by ApplyEnv
+const envPrefix = "OLLAMA_COMMIT_"
+
+// dotEnvKeys holds the variables LoadDotEnv set, which the repository
+// controls rather than the user's environment
+var dotEnvKeys = map[string]bool{}
+
+// isSecretEnv reports whether a variable holds a key or token, which a
+// repository's .env may not choose, like apiKeys in its config file
+func isSecretEnv(key string) bool {
+	for _, suffix := range []string{"_KEY", "_TOKEN", "_SECRET", "_PASSWORD"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDotEnv sets the OLLAMA_COMMIT_* variables from a .env file at the top
+// of the repository, or the current directory outside a repository. Variables
+// already set in the environment are left alone, and a missing file is not an
+// error. Keys and tokens such as OLLAMA_COMMIT_API_KEY are skipped and
+// reported in the error.
+func LoadDotEnv() error {
+	dir := "."
+	if output, err := gitCommand("rev-parse", "--show-toplevel").Output(); err == nil {
+		dir = strings.TrimSpace(string(output))
+	}
+
+	file, err := os.Open(filepath.Join(dir, ".env"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read .env: %w", err)
+	}
+	defer file.Close()
+
+	var skipped []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+		if isSecretEnv(key) {
+			skipped = append(skipped, key)
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		os.Setenv(key, unquote(strings.TrimSpace(value)))
+		dotEnvKeys[key] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read .env: %w", err)
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf(".env sets %s, which is only read from the environment; ignoring it", strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// unquote removes matching single or double quotes around a .env value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// ApplyEnv overrides the configuration with the OLLAMA_COMMIT_* environment
+// variables. Invalid values are reported by Validate.
+func (c *Config) ApplyEnv() {
+	if value := os.Getenv(envPrefix + "MODEL"); value != "" {
+		c.DefaultModel = value
+	}
+	if value := os.Getenv(envPrefix + "URL"); value != "" {
+		c.OllamaAPIURL = value
+		c.repoURL = ""
+		if dotEnvKeys[envPrefix+"URL"] {
+			c.repoURL = value
+		}
+	}
+	if value := os.Getenv(envPrefix + "PROMPT_TEMPLATE"); value != "" {
+		c.PromptTemplate = value
+	}
+	if value := os.Getenv(envPrefix + "PROVIDER"); value != "" {
+		c.Provider = value
+	}
+
+	for name, field := range map[string]*int{
+		"TIMEOUT":         &c.Timeout,
+		"CONNECT_TIMEOUT": &c.ConnectTimeout,
+	} {
+		value := os.Getenv(envPrefix + name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			c.loadErr = errors.Join(c.loadErr, fmt.Errorf("%s%s must be a number of seconds, got %q", envPrefix, name, value))
+			continue
+		}
+		*field = seconds
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDotEnvSkipsSecrets(t *testing.T) {
+	_, local := configDirs(t)
+	writeFile(t, filepath.Join(filepath.Dir(local), ".env"),
+		"OLLAMA_COMMIT_MODEL=mistral\nOLLAMA_COMMIT_API_KEY=sk-repo\nexport OLLAMA_COMMIT_SERVE_TOKEN=chosen\n")
+	for _, key := range []string{"OLLAMA_COMMIT_MODEL", "OLLAMA_COMMIT_API_KEY", "OLLAMA_COMMIT_SERVE_TOKEN"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Cleanup(func() { delete(dotEnvKeys, "OLLAMA_COMMIT_MODEL") })
+
+	err := LoadDotEnv()
+	if err == nil || !strings.Contains(err.Error(), "OLLAMA_COMMIT_API_KEY, OLLAMA_COMMIT_SERVE_TOKEN") {
+		t.Errorf("LoadDotEnv() error = %v, want the skipped variables reported", err)
+	}
+	if got := os.Getenv("OLLAMA_COMMIT_MODEL"); got != "mistral" {
+		t.Errorf("OLLAMA_COMMIT_MODEL = %q, want mistral from .env", got)
+	}
+	for _, key := range []string{"OLLAMA_COMMIT_API_KEY", "OLLAMA_COMMIT_SERVE_TOKEN"} {
+		if value, set := os.LookupEnv(key); set {
+			t.Errorf("%s = %q, want it left unset", key, value)
+		}
+	}
+}
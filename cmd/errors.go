@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Errors callers can check for with errors.Is
+var (
+	// ErrNoChanges is returned when there are no changes to describe
+	ErrNoChanges = errors.New("no changes to commit")
+	// ErrNotARepo is returned when git can't be run or the directory isn't a repository
+	ErrNotARepo = errors.New("not in a git repository or git is not installed")
+	// ErrModelNotFound is returned when the server doesn't have the requested model
+	ErrModelNotFound = errors.New("model not found")
+	// ErrAPIUnreachable is returned when the API can't be connected to
+	ErrAPIUnreachable = errors.New("failed to call the model API")
+)
+
+// OwnershipError is returned when git refuses to use a repository because it
+// is owned by another user
+type OwnershipError struct {
+	Dir string // Directory git was run in, the working directory if empty
+}
+
+func (e *OwnershipError) Error() string {
+	dir := e.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return fmt.Sprintf("git refuses to use this repository because it is owned by another user; "+
+		"run 'git config --global --add safe.directory %s' or use -safe-dir", dir)
+}
+
+// Is makes errors.Is(err, ErrNotARepo) match, since git can't use the repository
+func (e *OwnershipError) Is(target error) bool {
+	return target == ErrNotARepo
+}
+
+// Is makes errors.Is(err, ErrModelNotFound) match Ollama's 404 for a missing model
+func (e *StatusError) Is(target error) bool {
+	return target == ErrModelNotFound && e.StatusCode == 404 && strings.Contains(strings.ToLower(e.Body), "model")
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FinishOptions controls the cleanup every message from the model goes
+// through before it is shown or committed, whether it was generated, refined
+// or produced by a benchmark
+type FinishOptions struct {
+	PromptTemplate       string   // Prompt the message was generated with, to drop instructions the model echoed
+	SubjectSeparator     string   // How the model's subject is told from the body, SeparatorBlankLine if empty
+	EnforceImperative    bool     // Rewrite past tense subjects in imperative mood
+	SubjectCase          string   // Case of the subject's first letter
+	MaxBodyBullets       int      // Most bullet points kept in the body, 0 for no limit
+	Scope                string   // Conventional commit scope to add, none if empty
+	BreakingChangeFooter bool     // Normalize breaking change notes into a BREAKING CHANGE footer
+	Ticket               string   // Ticket for a Refs footer, none if empty
+	Trailers             []string // Extra trailers such as the verification result
+	CoAuthors            []string // Identities added as Co-authored-by trailers
+	MaxMessageBytes      int      // Most bytes in the message, 0 for no limit
+	LineEnding           string   // Line ending the message is committed with, for measuring its size
+	Verbose              bool     // Also report rewrites that aren't worth a warning
+}
+
+// FinishMessage applies the configured cleanup to a message from the model.
+// It returns the finished message along with notes about what was changed,
+// for the caller to print, or an error if the message can't be made to fit
+// MaxMessageBytes.
+func FinishMessage(message string, opts FinishOptions) (string, []string, error) {
+	var notes []string
+
+	// Drop prompt instructions the model echoed back
+	if cleaned, leaked := StripPromptLeaks(message, opts.PromptTemplate); leaked {
+		notes = append(notes, "Warning: the model echoed parts of the prompt instructions; they were removed from the message")
+		message = cleaned
+	}
+
+	// Make sure git sees the subject the way the configuration splits it
+	separator := opts.SubjectSeparator
+	if separator == "" {
+		separator = SeparatorBlankLine
+	}
+	message = JoinMessage(SplitMessage(message, separator))
+
+	// Fix the most common style slip, a past tense subject
+	if opts.EnforceImperative {
+		if fixed, changed := EnforceImperative(message); changed {
+			if opts.Verbose {
+				notes = append(notes, "Rewrote the subject in imperative mood")
+			}
+			message = fixed
+		}
+	}
+	message = ApplySubjectCase(message, opts.SubjectCase)
+
+	// Drop bullet points beyond the configured maximum
+	if limited, truncated := LimitBodyBullets(message, opts.MaxBodyBullets); truncated {
+		notes = append(notes, fmt.Sprintf("Warning: the body had more than %d bullet points; the extra ones were removed", opts.MaxBodyBullets))
+		message = limited
+	}
+
+	message = ApplyScope(message, opts.Scope)
+
+	// Add the conventional commit footers and trailers
+	if opts.BreakingChangeFooter {
+		message = NormalizeBreakingChangeFooter(message)
+	}
+	message = AddRefsFooter(message, opts.Ticket)
+	for _, trailer := range opts.Trailers {
+		if !strings.Contains(message, trailer) {
+			message = AppendFooter(message, trailer)
+		}
+	}
+	message = AddCoAuthors(message, opts.CoAuthors)
+
+	// Fit backends that cap the total message size
+	limited, truncated, err := LimitMessageBytes(message, opts.MaxMessageBytes, opts.LineEnding)
+	if err != nil {
+		return "", notes, err
+	}
+	if truncated {
+		notes = append(notes, fmt.Sprintf("Warning: the message was longer than %d bytes; it was shortened, keeping the footers", opts.MaxMessageBytes))
+		message = limited
+	}
+	return message, notes, nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFinishMessage(t *testing.T) {
+	opts := FinishOptions{
+		PromptTemplate:    "Write a concise git commit message for the following changes.\n\n%s",
+		EnforceImperative: true,
+		SubjectCase:       "sentence",
+		MaxBodyBullets:    1,
+		Scope:             "api",
+		Ticket:            "ABC-1",
+		Trailers:          []string{"Verified-by: go test (pass)"},
+		CoAuthors:         []string{"Jane Doe <jane@example.com>"},
+	}
+	message := "feat: added login\nWrite a concise git commit message for the following changes.\n\n- first\n- second"
+
+	got, notes, err := FinishMessage(message, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "feat(api): Add login\n\n- first\n\nRefs: ABC-1\nVerified-by: go test (pass)\nCo-authored-by: Jane Doe <jane@example.com>"
+	if got != want {
+		t.Errorf("FinishMessage() =\n%s\nwant\n%s", got, want)
+	}
+	if len(notes) != 2 {
+		t.Errorf("notes = %q, want the leak and bullet warnings", notes)
+	}
+
+	// Finishing twice, as when a refined message comes back, adds nothing
+	again, _, _ := FinishMessage(got, opts)
+	if again != got {
+		t.Errorf("second FinishMessage() =\n%s\nwant\n%s", again, got)
+	}
+}
+
+func TestFinishMessageImperative(t *testing.T) {
+	got, notes, _ := FinishMessage("added login\n\nbody", FinishOptions{EnforceImperative: true, SubjectCase: "sentence", Verbose: true})
+	if !strings.HasPrefix(got, "Add login") {
+		t.Errorf("FinishMessage() = %q, want an imperative subject", got)
+	}
+	if len(notes) != 1 {
+		t.Errorf("notes = %q, want the rewrite note", notes)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultTicketPattern matches issue keys like ABC-123 in branch names
+const defaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// breakingChangeInstructions asks the model for a BREAKING CHANGE footer when appropriate
+const breakingChangeInstructions = `
+
+If the changes break backwards compatibility (for example a removed or renamed public API,
+changed function signatures or changed configuration format), end the message with a footer line
+"BREAKING CHANGE: <what breaks and how to migrate>" after a blank line. Otherwise don't add it.`
+
+// footerLine matches a git trailer or conventional commit footer line
+var footerLine = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|BREAKING CHANGE): `)
+
+// breakingVariant matches the spellings models use for the breaking change footer
+var breakingVariant = regexp.MustCompile(`(?im)^\s*(?:[*-]\s*)?\**breaking[ _-]changes?\**\s*:\s*`)
+
+// WithBreakingChangeInstructions adds the breaking change footer instructions to a prompt template
+func WithBreakingChangeInstructions(promptTemplate string) string {
+	return promptTemplate + breakingChangeInstructions
+}
+
+// NormalizeBreakingChangeFooter rewrites variants such as "Breaking change:" to
+// the "BREAKING CHANGE:" token and moves the footer to the end of the message
+func NormalizeBreakingChangeFooter(message string) string {
+	var kept, breaking []string
+	for _, line := range strings.Split(message, "\n") {
+		if loc := breakingVariant.FindStringIndex(line); loc != nil {
+			breaking = append(breaking, "BREAKING CHANGE: "+strings.TrimSpace(line[loc[1]:]))
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	message = strings.TrimRight(strings.Join(kept, "\n"), "\n")
+	for _, footer := range breaking {
+		message = AppendFooter(message, footer)
+	}
+	return message
+}
+
+// TicketFromBranch returns the first match of pattern in the current branch name
+func TicketFromBranch(pattern string) string {
+	if pattern == "" {
+		pattern = defaultTicketPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ""
+	}
+
+	output, err := gitCommand("rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return re.FindString(strings.TrimSpace(string(output)))
+}
+
+// AddRefsFooter adds a "Refs: <ticket>" footer unless the message already references the ticket
+func AddRefsFooter(message, ticket string) string {
+	if ticket == "" || strings.Contains(message, "Refs: "+ticket) {
+		return message
+	}
+	return AppendFooter(message, "Refs: "+ticket)
+}
+
+// AppendFooter adds a footer line to the message, joining an existing footer
+// block or starting a new paragraph
+func AppendFooter(message, footer string) string {
+	message = strings.TrimRight(message, "\n")
+
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && isFooterBlock(last) {
+		return message + "\n" + footer
+	}
+	return message + "\n\n" + footer
+}
+
+// isFooterBlock reports whether every line of the paragraph is a footer
+func isFooterBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !footerLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
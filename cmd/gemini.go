@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// geminiMethod is the method of the Gemini API that generates text
+const geminiMethod = ":generateContent"
+
+func init() {
+	RegisterProvider("gemini", newGeminiProvider, func(apiURL *url.URL) bool {
+		return apiURL.Hostname() == "generativelanguage.googleapis.com" ||
+			strings.HasSuffix(apiURL.Path, geminiMethod)
+	})
+}
+
+// geminiProvider generates text with the Gemini generateContent API
+type geminiProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newGeminiProvider returns a Gemini provider. The URL is either the full
+// generateContent URL of a model or a base URL like
+// https://generativelanguage.googleapis.com/v1beta, completed with the model
+// of each prompt. The API key is read from OLLAMA_COMMIT_API_KEY,
+// GEMINI_API_KEY or apiKeys.gemini.
+func newGeminiProvider(client *Client, apiURL string) Provider {
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	return geminiProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("gemini", "GEMINI_API_KEY", apiURL, "generativelanguage.googleapis.com")}
+}
+
+// geminiPart is a part of the content of a Gemini request or response
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent is the content of a Gemini request or response
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiGenerationConfig holds the model parameters of a Gemini request
+type geminiGenerationConfig struct {
+	MaxOutputTokens    *int            `json:"maxOutputTokens,omitempty"`
+	Temperature        *float64        `json:"temperature,omitempty"`
+	TopP               *float64        `json:"topP,omitempty"`
+	Seed               *int            `json:"seed,omitempty"`
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+// geminiRequest is a generateContent request
+type geminiRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+// geminiResponse is a generateContent response
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// modelURL returns the generateContent URL of a model
+func (p geminiProvider) modelURL(model string) string {
+	if strings.HasSuffix(p.apiURL, geminiMethod) {
+		return p.apiURL
+	}
+	return p.apiURL + "/models/" + url.PathEscape(strings.TrimPrefix(model, "models/")) + geminiMethod
+}
+
+func (p geminiProvider) Name() string {
+	return "gemini"
+}
+
+func (p geminiProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the gemini provider needs an API key; set GEMINI_API_KEY or apiKeys.gemini")
+	}
+
+	req := geminiRequest{Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt.Text}}}}}
+	config := &req.GenerationConfig
+	if prompt.Options != nil {
+		config.MaxOutputTokens = prompt.Options.MaxTokens
+		config.Temperature = prompt.Options.Temperature
+		config.TopP = prompt.Options.TopP
+		config.Seed = prompt.Options.Seed
+		config.StopSequences = prompt.Options.Stop
+	}
+	// Ollama's "json" format is JSON mode, anything else a schema
+	if len(prompt.Format) > 0 {
+		config.ResponseMimeType = "application/json"
+		var mode string
+		if json.Unmarshal(prompt.Format, &mode) != nil || mode != "json" {
+			config.ResponseJSONSchema = prompt.Format
+		}
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("x-goog-api-key", p.apiKey)
+	bodyBytes, err := p.client.postRequest(ctx, p.modelURL(prompt.Model), reqBody, header)
+	if err != nil {
+		return "", err
+	}
+
+	var resp geminiResponse
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	p.client.reportUsage(Usage{PromptTokens: resp.UsageMetadata.PromptTokenCount, CompletionTokens: resp.UsageMetadata.CandidatesTokenCount})
+
+	var text strings.Builder
+	if len(resp.Candidates) > 0 {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			text.WriteString(part.Text)
+		}
+	}
+	if strings.TrimSpace(text.String()) == "" {
+		return "", fmt.Errorf("%w; check the model", ErrEmptyResponse)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import "strings"
+
+// DefaultGeneratedFiles are the lock and generated files left out of the diff
+// when none are configured. They make up much of a diff but say little about it.
+var DefaultGeneratedFiles = []string{
+	"package-lock.json",
+	"yarn.lock",
+	"go.sum",
+	"*.min.js",
+	"*_generated.go",
+	"*.pb.go",
+}
+
+// excludePathspecs turns gitignore-style patterns into pathspecs excluding
+// them. Patterns without a slash match in any directory.
+func excludePathspecs(patterns []string) []string {
+	pathspecs := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		pathspecs = append(pathspecs, ":(exclude,glob)"+pattern)
+	}
+	return pathspecs
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// shellCommand returns a command running line with the platform's shell,
+// killed when ctx is done
+func shellCommand(ctx context.Context, line string) *exec.Cmd {
+	var command *exec.Cmd
+	if runtime.GOOS == "windows" {
+		command = exec.CommandContext(ctx, "cmd", "/C", line)
+	} else {
+		command = exec.CommandContext(ctx, "sh", "-c", line)
+	}
+	// Don't wait for children of the shell still holding the output open
+	command.WaitDelay = time.Second
+	return command
+}
+
+// RunGenerator runs an external generator command instead of calling Ollama.
+// The diff is written to its stdin and the model, API URL and prompt
+// template are passed in the same OLLAMA_COMMIT_* environment variables that
+// override them for ollama-commit itself. Its stdout becomes the commit message.
+func RunGenerator(command, gitDiff, model, apiURL, promptTemplate string) (string, error) {
+	generator := shellCommand(context.Background(), command)
+	generator.Stdin = strings.NewReader(gitDiff)
+	generator.Env = append(os.Environ(),
+		envPrefix+"MODEL="+model,
+		envPrefix+"URL="+apiURL,
+		envPrefix+"PROMPT_TEMPLATE="+promptTemplate,
+	)
+
+	var stderr bytes.Buffer
+	generator.Stderr = &stderr
+	output, err := generator.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("generator command failed: %v: %s", err, msg)
+		}
+		return "", fmt.Errorf("generator command failed: %v", err)
+	}
+
+	message := strings.TrimSpace(string(output))
+	if message == "" {
+		return "", fmt.Errorf("generator command printed no commit message")
+	}
+	return message, nil
+}
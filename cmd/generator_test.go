@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRunGeneratorEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	got, err := RunGenerator(`echo "$OLLAMA_COMMIT_MODEL $OLLAMA_COMMIT_URL $(cat)"`, "diff", "llama3", "http://gpu:11434/api/generate", "%s")
+	if err != nil {
+		t.Fatalf("RunGenerator() error = %v", err)
+	}
+	if want := "llama3 http://gpu:11434/api/generate diff"; got != want {
+		t.Errorf("RunGenerator() = %q, want %q", got, want)
+	}
+}
@@ -2,45 +2,346 @@ package cmd
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
-// GetGitDiff retrieves git diff from the repository
-func GetGitDiff() (string, error) {
+// gitGlobalArgs are passed to every git invocation before the subcommand
+var gitGlobalArgs []string
+
+// gitCommand builds a git command with the global arguments applied
+func gitCommand(args ...string) *exec.Cmd {
+	return exec.Command("git", append(append([]string{}, gitGlobalArgs...), args...)...)
+}
+
+// gitCommandIn builds a git command running in dir, or the working directory if dir is empty
+func gitCommandIn(dir string, args ...string) *exec.Cmd {
+	command := gitCommand(args...)
+	command.Dir = dir
+	return command
+}
+
+// gitOutput runs a git command in dir, or the working directory if dir is
+// empty, and returns its output with any error explained by gitError
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	output, err := gitCommandIn(dir, args...).Output()
+	return output, gitError(dir, err)
+}
+
+// gitError explains why a git command failed: ErrNotARepo if git is missing
+// or the directory isn't a repository, an OwnershipError if git refuses a
+// repository owned by another user, and git's own message otherwise
+func gitError(dir string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrNotARepo
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	stderr := strings.TrimSpace(string(exitErr.Stderr))
+	switch {
+	case strings.Contains(stderr, "dubious ownership"):
+		return &OwnershipError{Dir: dir}
+	case strings.Contains(stderr, "not a git repository"):
+		return ErrNotARepo
+	case stderr != "":
+		return fmt.Errorf("%w: %s", err, stderr)
+	}
+	return err
+}
+
+// gitConfigValue returns a git config value, or an empty string if unset
+func gitConfigValue(key string) string {
+	output, err := gitCommand("config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// TrustAllDirectories makes git skip its safe.directory ownership check for
+// this invocation, which is often needed in CI containers
+func TrustAllDirectories() {
+	gitGlobalArgs = append(gitGlobalArgs, "-c", "safe.directory=*")
+}
+
+// lastMessageFile is the file inside the git directory holding the last generated message
+const lastMessageFile = "OLLAMA_COMMIT_LAST"
+
+// emptyTree is the hash of git's empty tree, used as the baseline before the first commit
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// DiffOptions controls how the git diff is gathered
+type DiffOptions struct {
+	FindRenames      int      // Similarity threshold in percent for rename detection, 0 uses git's default
+	FindCopies       bool     // Detect copies as well as renames
+	Base             string   // Ref to diff against instead of HEAD
+	Plumbing         bool     // Use diff-index/diff-files instead of git diff, ignoring the user's diff config
+	Paths            []string // Limit the diff to these pathspecs
+	IgnoreWhitespace bool     // Leave out whitespace-only changes
+	Exclude          []string // Leave out files matching these patterns, unless nothing else changed
+	Dir              string   // Repository to diff, the working directory if empty
+}
+
+// diffArgs returns the git arguments for diffing the staged or unstaged
+// changes, with format selecting the output (e.g. "-p" or "--name-only")
+func (o DiffOptions) diffArgs(staged bool, format string) []string {
+	var args []string
+	switch {
+	case o.Plumbing && staged:
+		args = []string{"diff-index", "--cached"}
+	case o.Plumbing && o.Base != "":
+		args = []string{"diff-index"}
+	case o.Plumbing:
+		args = []string{"diff-files"}
+	case staged:
+		args = []string{"diff", "--staged"}
+	default:
+		args = []string{"diff"}
+	}
+	// Never let color.diff=always leak escape codes into the prompt
+	args = append(args, format, "--no-color")
+
+	args = append(args, o.detectionArgs()...)
+
+	// diff-index always needs a tree to compare against
+	if o.Base != "" {
+		args = append(args, o.Base)
+	} else if o.Plumbing && staged {
+		args = append(args, headOrEmptyTree(o.Dir))
+	}
+
+	if len(o.Paths) > 0 || len(o.Exclude) > 0 {
+		args = append(args, "--")
+		args = append(args, o.Paths...)
+		args = append(args, excludePathspecs(o.Exclude)...)
+	}
+	return args
+}
+
+// detectionArgs returns the rename detection and whitespace options shared by all diff commands
+func (o DiffOptions) detectionArgs() []string {
+	var args []string
+	if o.FindRenames > 0 {
+		args = append(args, fmt.Sprintf("--find-renames=%d%%", o.FindRenames))
+	}
+	if o.FindCopies {
+		args = append(args, "--find-copies")
+	}
+	if o.IgnoreWhitespace {
+		args = append(args, "--ignore-all-space", "--ignore-blank-lines")
+	}
+	return args
+}
+
+// headOrEmptyTree returns HEAD of the repository in dir, or the empty tree if
+// there are no commits yet
+func headOrEmptyTree(dir string) string {
+	if err := gitCommandIn(dir, "rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+		return emptyTree
+	}
+	return "HEAD"
+}
+
+// GetGitDiff retrieves git diff from the repository, returning ErrNoChanges if there is nothing to commit
+func GetGitDiff(opts DiffOptions) (string, error) {
 	// Check if in a git repository
-	cmdStatus := exec.Command("git", "status")
-	if err := cmdStatus.Run(); err != nil {
-		return "", fmt.Errorf("not in a git repository or git is not installed")
+	if _, err := gitOutput(opts.Dir, "status"); err != nil {
+		var ownership *OwnershipError
+		if errors.As(err, &ownership) {
+			return "", err
+		}
+		return "", ErrNotARepo
+	}
+
+	// Make sure the base ref points at a commit
+	if opts.Base != "" {
+		if err := verifyRef(opts.Dir, opts.Base); err != nil {
+			return "", err
+		}
 	}
 
-	// Get staged changes
-	cmdDiff := exec.Command("git", "diff", "--staged")
-	diffOutput, err := cmdDiff.Output()
+	// Make sure the paths are known to git
+	if len(opts.Paths) > 0 {
+		if err := verifyPaths(opts.Dir, opts.Paths); err != nil {
+			return "", err
+		}
+	}
+
+	diffOutput, err := opts.runDiff("-p")
 	if err != nil {
-		return "", fmt.Errorf("failed to get git diff: %v", err)
+		return "", fmt.Errorf("failed to get git diff: %w", err)
+	}
+	if diffOutput == "" {
+		return "", ErrNoChanges
 	}
+	return diffOutput, nil
+}
 
-	// If no staged changes, try to get unstaged changes
-	if len(diffOutput) == 0 {
-		cmdDiff = exec.Command("git", "diff")
-		diffOutput, err = cmdDiff.Output()
+// runDiff runs git diff with the given output format on the staged changes,
+// falling back to the unstaged changes if nothing is staged
+func (o DiffOptions) runDiff(format string) (string, error) {
+	output, err := o.diffExcluding(true, format)
+	if err != nil {
+		return "", err
+	}
+	if len(output) == 0 {
+		output, err = o.diffExcluding(false, format)
 		if err != nil {
-			return "", fmt.Errorf("failed to get git diff: %v", err)
+			return "", err
 		}
 	}
+	return string(output), nil
+}
 
-	return string(diffOutput), nil
+// diffExcluding runs git diff on the staged or unstaged changes without the
+// excluded files. If only excluded files changed, they are described after all.
+func (o DiffOptions) diffExcluding(staged bool, format string) ([]byte, error) {
+	output, err := gitOutput(o.Dir, o.diffArgs(staged, format)...)
+	if err != nil || len(output) > 0 || len(o.Exclude) == 0 {
+		return output, err
+	}
+	o.Exclude = nil
+	return gitOutput(o.Dir, o.diffArgs(staged, format)...)
+}
+
+// GetGitDiffStat returns a summary of the changes (git diff --stat) for when the full diff is too large
+func GetGitDiffStat(opts DiffOptions) (string, error) {
+	output, err := opts.runDiff("--stat")
+	if err != nil {
+		return "", fmt.Errorf("failed to get git diff stat: %w", err)
+	}
+	return output, nil
+}
+
+// FileChange is a changed file as reported by git diff --name-status
+type FileChange struct {
+	Status  byte   // A, M, D, R, C, T, ...
+	Path    string // Path after the change
+	OldPath string // Path before a rename or copy
+}
+
+// GetFileChanges returns the changed files with their change type
+func GetFileChanges(opts DiffOptions) ([]FileChange, error) {
+	output, err := opts.runDiff("--name-status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed files: %w", err)
+	}
+
+	var changes []FileChange
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+
+		change := FileChange{Status: fields[0][0], Path: fields[len(fields)-1]}
+		if len(fields) == 3 {
+			change.OldPath = fields[1]
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// NameStatusContext returns the --name-status listing of the changes as a
+// preamble for the diff, so the model sees each file's change type
+// explicitly. It returns an empty string if the listing can't be read.
+func NameStatusContext(opts DiffOptions) string {
+	output, err := opts.runDiff("--name-status")
+	if err != nil || strings.TrimSpace(output) == "" {
+		return ""
+	}
+	return "Changed files (A added, M modified, D deleted, R renamed, C copied, T type changed):\n" +
+		strings.TrimRight(output, "\n") + "\n\nDiff:\n"
+}
+
+// meaningfulDiffPrefixes start diff lines that show an actual change rather than just a file header
+var meaningfulDiffPrefixes = []string{"@@", "Binary files", "GIT binary patch", "new file mode", "deleted file mode", "old mode", "rename from", "copy from"}
+
+// HasMeaningfulChanges reports whether the diff contains any change, as
+// opposed to only file headers left over after whitespace filtering
+func HasMeaningfulChanges(diff string) bool {
+	for _, line := range strings.Split(diff, "\n") {
+		for _, prefix := range meaningfulDiffPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DiffHash returns a fingerprint of a diff, used to detect changes to it
+func DiffHash(diff string) string {
+	sum := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRef checks that ref resolves to a commit
+func VerifyRef(ref string) error {
+	return verifyRef("", ref)
+}
+
+// verifyRef checks that ref resolves to a commit in the repository in dir
+func verifyRef(dir, ref string) error {
+	cmdVerify := gitCommandIn(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err := cmdVerify.Run(); err != nil {
+		return fmt.Errorf("invalid base ref %q: not a commit", ref)
+	}
+	return nil
+}
+
+// VerifyPaths checks that every pathspec matches a file in the index or HEAD
+func VerifyPaths(paths []string) error {
+	return verifyPaths("", paths)
+}
+
+// verifyPaths checks the pathspecs against the repository in dir
+func verifyPaths(dir string, paths []string) error {
+	for _, path := range paths {
+		cmdLs := gitCommandIn(dir, "ls-files", "--error-unmatch", "--with-tree="+headOrEmptyTree(dir), "--", path)
+		if err := cmdLs.Run(); err != nil {
+			return fmt.Errorf("path %q did not match any file known to git", path)
+		}
+	}
+	return nil
+}
+
+// stdinReader is shared by all prompts so buffered input isn't lost between them
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// StdinIsTerminal reports whether someone can answer prompts on stdin
+func StdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ReadLine prints a prompt and returns the line the user typed
+func ReadLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	input, err := stdinReader.ReadString('\n')
+	if err != nil && input == "" {
+		return "", err
+	}
+	return strings.TrimSpace(input), nil
 }
 
 // ConfirmCommit asks the user to confirm the commit message
 func ConfirmCommit(message string) bool {
-	reader := bufio.NewReader(os.Stdin)
-
 	fmt.Print("Are you sure you want to use this commit message? (y/n): ")
-	input, err := reader.ReadString('\n')
+	input, err := stdinReader.ReadString('\n')
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
 		return false
@@ -50,10 +351,149 @@ func ConfirmCommit(message string) bool {
 	return input == "y" || input == "yes"
 }
 
+// CommitOptions controls how ExecuteGitCommit creates the commit
+type CommitOptions struct {
+	Paths    []string // Only commit these paths, like git commit -- <paths>
+	Encoding string   // Encoding the message is converted to and recorded in the commit header, UTF-8 if empty
+}
+
+// AskCommitAction asks whether to use the commit message. It returns "y" to
+// commit, "s" to regenerate only the subject (offered when allowSubject is
+// set) or "n" to abort.
+func AskCommitAction(allowSubject bool) string {
+	prompt := "Are you sure you want to use this commit message? (y/n): "
+	if allowSubject {
+		prompt = "Are you sure you want to use this commit message? (y/n, s to regenerate the subject): "
+	}
+
+	fmt.Print(prompt)
+	input, err := stdinReader.ReadString('\n')
+	if err != nil && input == "" {
+		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		return "n"
+	}
+
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "y", "yes":
+		return "y"
+	case "s", "subject":
+		if allowSubject {
+			return "s"
+		}
+	}
+	return "n"
+}
+
 // ExecuteGitCommit performs the git commit with the given message
-func ExecuteGitCommit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
+func ExecuteGitCommit(message string, opts CommitOptions) error {
+	// git's default cleanup strips the \r of CRLF line endings as trailing
+	// whitespace, so such messages are committed verbatim, final newline included
+	verbatim := strings.Contains(message, "\r\n")
+	if verbatim && !strings.HasSuffix(message, "\r\n") {
+		message += "\r\n"
+	}
+
+	data, err := EncodeMessage(message, opts.Encoding)
+	if err != nil {
+		return err
+	}
+
+	// Pass the message through a file to avoid argument length limits
+	file, err := CreateTempFile("msg-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create commit message file: %w", err)
+	}
+	defer RemoveTempFile(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+
+	var args []string
+	if !isUTF8(opts.Encoding) {
+		// Tell git which encoding the message file uses so it writes the header
+		args = append(args, "-c", "i18n.commitEncoding="+opts.Encoding)
+	}
+	args = append(args, "commit", "-F", file.Name())
+	if verbatim {
+		args = append(args, "--cleanup=verbatim")
+	}
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Paths...)
+	}
+
+	cmd := gitCommand(args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+// CommittedMessage returns the message of the HEAD commit
+func CommittedMessage() (string, error) {
+	output, err := gitCommand("log", "-1", "--pretty=%B").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the committed message: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// SameMessage reports whether two commit messages are equal after the
+// whitespace cleanup git applies when committing
+func SameMessage(a, b string) bool {
+	return cleanupMessage(a) == cleanupMessage(b)
+}
+
+// cleanupMessage normalizes line endings and trailing whitespace like git's
+// default whitespace cleanup
+func cleanupMessage(message string) string {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// gitDir returns the path of the repository's .git directory
+func gitDir() (string, error) {
+	output, err := gitOutput("", "rev-parse", "--git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// SaveLastMessage stores the generated message so it can be reused later
+func SaveLastMessage(message string) error {
+	dir, err := gitDir()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, lastMessageFile), []byte(message+"\n"), 0644)
+}
+
+// LoadLastMessage returns the message stored by the previous run
+func LoadLastMessage() (string, error) {
+	dir, err := gitDir()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, lastMessageFile))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no saved commit message found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read saved commit message: %w", err)
+	}
+
+	message := strings.TrimSpace(string(data))
+	if message == "" {
+		return "", fmt.Errorf("saved commit message is empty")
+	}
+	return message, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository in a temporary directory with one commit,
+// skipping the test if git isn't installed
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	writeFile(t, filepath.Join(dir, "file.txt"), "one\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+// runGit runs git in dir, failing the test on error
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	output, err := command.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetGitDiffNoColor(t *testing.T) {
+	dir := newTestRepo(t)
+	runGit(t, dir, "config", "color.ui", "always")
+	runGit(t, dir, "config", "color.diff", "always")
+	writeFile(t, filepath.Join(dir, "file.txt"), "one\ntwo\n")
+
+	for _, staged := range []bool{false, true} {
+		if staged {
+			runGit(t, dir, "add", ".")
+		}
+		for _, plumbing := range []bool{false, true} {
+			diff, err := GetGitDiff(DiffOptions{Dir: dir, Plumbing: plumbing})
+			if err != nil {
+				t.Fatalf("GetGitDiff() error = %v", err)
+			}
+			if strings.Contains(diff, "\x1b[") {
+				t.Errorf("diff (staged %v, plumbing %v) contains escape codes: %q", staged, plumbing, diff)
+			}
+			if !strings.Contains(diff, "+two") {
+				t.Errorf("diff (staged %v, plumbing %v) is missing the change: %q", staged, plumbing, diff)
+			}
+		}
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func TestExecuteGitCommitLineEndings(t *testing.T) {
+	message := "Add two\n\nExplain why.\n\nRefs: ABC-1"
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{LineEndingLF, "Add two\n\nExplain why.\n\nRefs: ABC-1\n"},
+		{LineEndingCRLF, "Add two\r\n\r\nExplain why.\r\n\r\nRefs: ABC-1\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			dir := newTestRepo(t)
+			chdir(t, dir)
+			writeFile(t, filepath.Join(dir, "file.txt"), "one\ntwo\n")
+			runGit(t, dir, "add", ".")
+
+			if err := ExecuteGitCommit(ApplyLineEnding(message, tt.style), CommitOptions{}); err != nil {
+				t.Fatalf("ExecuteGitCommit() error = %v", err)
+			}
+			if got := runGit(t, dir, "log", "-1", "--format=%B"); got != tt.want+"\n" {
+				t.Errorf("committed message = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitError(t *testing.T) {
+	exitError := func(stderr string) error {
+		return &exec.ExitError{ProcessState: &os.ProcessState{}, Stderr: []byte(stderr)}
+	}
+
+	var ownership *OwnershipError
+	err := gitError("/srv/repo", exitError("fatal: detected dubious ownership in repository at '/srv/repo'"))
+	if !errors.As(err, &ownership) || !strings.Contains(err.Error(), "safe.directory /srv/repo") {
+		t.Errorf("dubious ownership: gitError() = %v, want an OwnershipError for /srv/repo", err)
+	}
+	if !errors.Is(err, ErrNotARepo) {
+		t.Errorf("dubious ownership: gitError() = %v, want it to match ErrNotARepo", err)
+	}
+	if err := gitError("", exitError("fatal: not a git repository (or any of the parent directories): .git")); !errors.Is(err, ErrNotARepo) {
+		t.Errorf("not a repository: gitError() = %v, want ErrNotARepo", err)
+	}
+	if err := gitError("", exec.ErrNotFound); !errors.Is(err, ErrNotARepo) {
+		t.Errorf("git missing: gitError() = %v, want ErrNotARepo", err)
+	}
+	if err := gitError("", exitError("fatal: bad revision 'nope'")); !strings.Contains(err.Error(), "bad revision") {
+		t.Errorf("other failure: gitError() = %v, want git's message", err)
+	}
+}
+
+func TestGetGitDiffNotARepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	if _, err := GetGitDiff(DiffOptions{Dir: t.TempDir()}); !errors.Is(err, ErrNotARepo) {
+		t.Errorf("GetGitDiff() error = %v, want ErrNotARepo", err)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// groqAPIURL is Groq's OpenAI-compatible chat completions endpoint
+const groqAPIURL = "https://api.groq.com/openai/v1/chat/completions"
+
+func init() {
+	RegisterProvider("groq", newGroqProvider, func(apiURL *url.URL) bool {
+		return apiURL.Hostname() == "api.groq.com"
+	})
+}
+
+// groqProvider generates text with Groq's OpenAI-compatible API
+type groqProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newGroqProvider returns a Groq provider, sending requests to Groq's
+// endpoint unless another API URL is configured. A base URL is completed with
+// /chat/completions. The API key is read from OLLAMA_COMMIT_API_KEY,
+// GROQ_API_KEY or apiKeys.groq.
+func newGroqProvider(client *Client, apiURL string) Provider {
+	apiURL = orDefaultURL(apiURL, groqAPIURL)
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), chatCompletionsPath) {
+		apiURL = strings.TrimSuffix(apiURL, "/") + chatCompletionsPath
+	}
+	return groqProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("groq", "GROQ_API_KEY", apiURL, "api.groq.com")}
+}
+
+func (p groqProvider) endpoint() string {
+	return p.apiURL
+}
+
+func (p groqProvider) Name() string {
+	return "groq"
+}
+
+func (p groqProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the groq provider needs an API key; set GROQ_API_KEY or apiKeys.groq")
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.apiKey)
+	return p.client.sendChatRequest(ctx, p.apiURL, header, prompt)
+}
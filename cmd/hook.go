@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HookName is the git hook ollama-commit installs
+const HookName = "prepare-commit-msg"
+
+// hookMarker identifies a hook written by ollama-commit
+const hookMarker = "# Installed by ollama-commit"
+
+// chainedHookSuffix is added to the name of a hook that was there before
+// ollama-commit's, which then runs it first
+const chainedHookSuffix = ".pre-ollama-commit"
+
+// hookScript fills in the message of a plain 'git commit' (no -m, -F, merge,
+// squash or amend), leaving the template's comments below it. Nobody can
+// answer prompts, so stdin is an empty pipe. A failure never blocks the
+// commit; the message is just left empty.
+const hookScript = `#!/bin/sh
+` + hookMarker + `; remove it with 'ollama-commit hook uninstall'
+chained="$(dirname "$0")/` + HookName + chainedHookSuffix + `"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+
+[ -z "$2" ] || exit 0
+
+generated="$1.ollama-commit"
+if true | %s -quiet -o "$generated" && [ -s "$generated" ]; then
+	cat "$1" >>"$generated" && mv "$generated" "$1"
+fi
+rm -f "$generated"
+exit 0
+`
+
+// HookStatus describes the prepare-commit-msg hook of the repository
+type HookStatus struct {
+	Path      string // Where the hook is, honoring core.hooksPath
+	Installed bool   // The hook is ollama-commit's
+	Foreign   bool   // Another hook is installed instead
+	Chained   string // The hook ollama-commit's runs first, if any
+}
+
+// hooksDir returns the directory git runs the repository's hooks from
+func hooksDir() (string, error) {
+	output, err := gitOutput("", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isOurHook reports whether the hook at path was written by ollama-commit
+func isOurHook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), hookMarker)
+}
+
+// GetHookStatus reports whether the hook is installed
+func GetHookStatus() (HookStatus, error) {
+	dir, err := hooksDir()
+	if err != nil {
+		return HookStatus{}, err
+	}
+	status := HookStatus{Path: filepath.Join(dir, HookName)}
+	if _, err := os.Stat(status.Path); err == nil {
+		status.Installed = isOurHook(status.Path)
+		status.Foreign = !status.Installed
+	}
+	if _, err := os.Stat(status.Path + chainedHookSuffix); err == nil {
+		status.Chained = status.Path + chainedHookSuffix
+	}
+	return status, nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// InstallHook writes the prepare-commit-msg hook running executable. An
+// existing hook is kept and run before it.
+func InstallHook(executable string) (HookStatus, error) {
+	status, err := GetHookStatus()
+	if err != nil {
+		return status, err
+	}
+	if err := os.MkdirAll(filepath.Dir(status.Path), 0755); err != nil {
+		return status, fmt.Errorf("failed to create %s: %w", filepath.Dir(status.Path), err)
+	}
+
+	if status.Foreign {
+		if status.Chained != "" {
+			return status, fmt.Errorf("%s already exists, so %s can't be moved aside", status.Chained, status.Path)
+		}
+		if err := os.Rename(status.Path, status.Path+chainedHookSuffix); err != nil {
+			return status, fmt.Errorf("failed to move the existing hook aside: %w", err)
+		}
+		status.Chained = status.Path + chainedHookSuffix
+	}
+
+	script := fmt.Sprintf(hookScript, shellQuote(filepath.ToSlash(executable)))
+	if err := WriteFileAtomic(status.Path, []byte(script), 0755); err != nil {
+		return status, fmt.Errorf("failed to write %s: %w", status.Path, err)
+	}
+	status.Installed, status.Foreign = true, false
+	return status, nil
+}
+
+// UninstallHook removes ollama-commit's hook and puts back the hook it ran
+// first, if any. It returns false if the hook wasn't installed.
+func UninstallHook() (bool, error) {
+	status, err := GetHookStatus()
+	if err != nil {
+		return false, err
+	}
+	if !status.Installed {
+		return false, nil
+	}
+	if err := os.Remove(status.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to remove %s: %w", status.Path, err)
+	}
+	if status.Chained != "" {
+		if err := os.Rename(status.Chained, status.Path); err != nil {
+			return true, fmt.Errorf("failed to restore %s: %w", status.Chained, err)
+		}
+	}
+	return true, nil
+}
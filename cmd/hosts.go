@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetAllowedHosts restricts API requests, including redirects, to the given
+// hosts. Entries are host names, optionally with a port.
+func (c *Client) SetAllowedHosts(hosts []string) {
+	c.allowedHosts = hosts
+}
+
+// CheckHostAllowed returns an error if the URL's host isn't in the allowlist
+func CheckHostAllowed(apiURL string, allowed []string) error {
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return fmt.Errorf("invalid API URL %q: %v", apiURL, err)
+	}
+	return checkHost(parsed, allowed)
+}
+
+// checkHost matches the URL's host name, or host and port, against the allowlist
+func checkHost(u *url.URL, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, host := range allowed {
+		if strings.EqualFold(host, u.Hostname()) || strings.EqualFold(host, u.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not in allowedHosts (%s); refusing to send the changes there", u.Host, strings.Join(allowed, ", "))
+}
+
+// IsLocalURL reports whether the URL points at this machine
+func IsLocalURL(apiURL string) bool {
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostCheckTransport refuses requests to hosts outside the client's allowlist
+type hostCheckTransport struct {
+	next   http.RoundTripper
+	client *Client
+}
+
+// RoundTrip checks the host before passing the request on
+func (t hostCheckTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkHost(req.URL, t.client.allowedHosts); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+	"unicode"
+)
+
+// imperativeVerbs maps past tense, third person and gerund forms of verbs
+// common at the start of commit subjects to the imperative
+var imperativeVerbs = map[string]string{
+	"added": "add", "adds": "add", "adding": "add",
+	"fixed": "fix", "fixes": "fix", "fixing": "fix",
+	"updated": "update", "updates": "update", "updating": "update",
+	"removed": "remove", "removes": "remove", "removing": "remove",
+	"changed": "change", "changes": "change", "changing": "change",
+	"implemented": "implement", "implements": "implement", "implementing": "implement",
+	"refactored": "refactor", "refactors": "refactor", "refactoring": "refactor",
+	"improved": "improve", "improves": "improve", "improving": "improve",
+	"created": "create", "creates": "create", "creating": "create",
+	"deleted": "delete", "deletes": "delete", "deleting": "delete",
+	"renamed": "rename", "renames": "rename", "renaming": "rename",
+	"moved": "move", "moves": "move", "moving": "move",
+	"replaced": "replace", "replaces": "replace", "replacing": "replace",
+	"introduced": "introduce", "introduces": "introduce", "introducing": "introduce",
+	"bumped": "bump", "bumps": "bump", "bumping": "bump",
+	"upgraded": "upgrade", "upgrades": "upgrade", "upgrading": "upgrade",
+	"cleaned": "clean", "cleans": "clean", "cleaning": "clean",
+	"corrected": "correct", "corrects": "correct", "correcting": "correct",
+	"adjusted": "adjust", "adjusts": "adjust", "adjusting": "adjust",
+	"enabled": "enable", "enables": "enable", "enabling": "enable",
+	"disabled": "disable", "disables": "disable", "disabling": "disable",
+	"simplified": "simplify", "simplifies": "simplify", "simplifying": "simplify",
+	"allowed": "allow", "allows": "allow", "allowing": "allow",
+	"documented": "document", "documents": "document", "documenting": "document",
+	"extracted": "extract", "extracts": "extract", "extracting": "extract",
+	"handled": "handle", "handles": "handle", "handling": "handle",
+	"ensured": "ensure", "ensures": "ensure", "ensuring": "ensure",
+	"supported": "support", "supports": "support", "supporting": "support",
+	"reverted": "revert", "reverts": "revert", "reverting": "revert",
+	"merged": "merge", "merges": "merge", "merging": "merge",
+	"optimized": "optimize", "optimizes": "optimize", "optimizing": "optimize",
+	"made": "make", "makes": "make", "making": "make",
+	"wrote": "write", "writes": "write", "writing": "write",
+}
+
+// EnforceImperative rewrites a subject starting with a past tense, third
+// person or gerund verb ("Added", "Fixes", "Updating") to the imperative
+// ("Add", "Fix", "Update"), keeping any type prefix and the verb's
+// capitalization. It reports whether the subject was changed.
+func EnforceImperative(message string) (string, bool) {
+	subject, rest, hasBody := strings.Cut(message, "\n")
+	prefix := subjectPrefix.FindString(subject)
+	description := subject[len(prefix):]
+
+	end := strings.IndexFunc(description, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end == -1 {
+		end = len(description)
+	}
+	word := description[:end]
+
+	// A word joined to the next one, like "Fixed-width" or "CHANGES.md", isn't a verb
+	if joined := description[end:]; len(joined) > 1 && strings.ContainsRune("-_/.'", rune(joined[0])) && !unicode.IsSpace(rune(joined[1])) {
+		return message, false
+	}
+
+	imperative, ok := imperativeVerbs[strings.ToLower(word)]
+	if !ok {
+		return message, false
+	}
+	if first := []rune(word)[0]; unicode.IsUpper(first) {
+		imperative = strings.ToUpper(imperative[:1]) + imperative[1:]
+	}
+
+	subject = prefix + imperative + description[end:]
+	if hasBody {
+		return subject + "\n" + rest, true
+	}
+	return subject, true
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestEnforceImperative(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Added login page", "Add login page"},
+		{"fixes crash on empty diff", "fix crash on empty diff"},
+		{"Updating dependencies\n\nAdded a body line", "Update dependencies\n\nAdded a body line"},
+		{"Simplified parser", "Simplify parser"},
+		{"Wrote tests", "Write tests"},
+		{"feat(api): added login", "feat(api): add login"},
+		{"fix!: Removed deprecated flag", "fix!: Remove deprecated flag"},
+		{":bug: Fixed crash", ":bug: Fix crash"},
+		{"Fixes #123", "Fix #123"},
+
+		// Already imperative, not a listed verb, or not a verb at all
+		{"Add login page", "Add login page"},
+		{"Addresses review comments", "Addresses review comments"},
+		{"Fixtures for the parser tests", "Fixtures for the parser tests"},
+		{"Fixed-width font in the diff view", "Fixed-width font in the diff view"},
+		{"CHANGES.md gets the release notes", "CHANGES.md gets the release notes"},
+		{"added_at column for users", "added_at column for users"},
+		{"Note: added login", "Note: added login"},
+		{"Login page added", "Login page added"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, changed := EnforceImperative(tt.message)
+		if got != tt.want {
+			t.Errorf("EnforceImperative(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+		if changed != (tt.message != tt.want) {
+			t.Errorf("EnforceImperative(%q) reported changed = %v", tt.message, changed)
+		}
+	}
+}
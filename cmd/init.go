@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RunInit walks the user through creating a configuration, starting from the
+// given one, and returns the result. The server is probed with client.
+func RunInit(config Config, client *Client) (Config, error) {
+	fmt.Println("This will create a configuration file for ollama-commit.")
+	fmt.Println("Press Enter to keep the value in brackets.")
+	fmt.Println()
+
+	// API URL, checking that the server answers
+	var models []ModelInfo
+	for {
+		input, err := ReadLine(fmt.Sprintf("Ollama API URL [%s]: ", config.OllamaAPIURL))
+		if err != nil {
+			return config, err
+		}
+		if input != "" {
+			config.OllamaAPIURL = input
+		}
+
+		provider, err := NewProvider(client, config.Provider, config.OllamaAPIURL)
+		if err == nil && !CanListModels(provider) {
+			fmt.Printf("The %s provider can't list its models, so the URL wasn't checked.\n\n", provider.Name())
+			break
+		}
+		if err == nil {
+			models, err = ListModels(provider)
+		}
+		if err == nil {
+			fmt.Printf("Found Ollama with %d installed model(s).\n\n", len(models))
+			break
+		}
+		fmt.Printf("Could not reach Ollama: %v\n", err)
+
+		answer, err := ReadLine("Use this URL anyway? (y/n): ")
+		if err != nil {
+			return config, err
+		}
+		if strings.ToLower(answer) == "y" || strings.ToLower(answer) == "yes" {
+			fmt.Println()
+			break
+		}
+	}
+
+	// Model, offering the installed ones
+	for i, model := range models {
+		fmt.Printf("  %d) %s\n", i+1, model.Name)
+	}
+	for {
+		input, err := ReadLine(fmt.Sprintf("Model (name or number) [%s]: ", config.DefaultModel))
+		if err != nil {
+			return config, err
+		}
+
+		name := config.DefaultModel
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(models) {
+			name = models[n-1].Name
+		} else if input != "" {
+			name = input
+		}
+
+		if models == nil || HasModel(models, name) {
+			config.DefaultModel = name
+			break
+		}
+		fmt.Printf("Model %q is not installed. Pull it with 'ollama pull %s' or choose another one.\n", name, name)
+	}
+	fmt.Println()
+
+	// Prompt template preset
+	fmt.Printf("Prompt templates: %s\n", strings.Join(PromptPresetNames(), ", "))
+	for {
+		input, err := ReadLine("Prompt template [keep current]: ")
+		if err != nil {
+			return config, err
+		}
+		if input == "" {
+			break
+		}
+
+		template, err := GetPromptPreset(input)
+		if err == nil {
+			config.PromptTemplate = template
+			break
+		}
+		fmt.Println(err)
+	}
+	fmt.Println()
+
+	return config, nil
+}
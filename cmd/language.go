@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// LanguageAuto detects the language from the comments in the diff
+const LanguageAuto = "auto"
+
+// minScriptLetters is how many letters of a non-Latin script are needed to pick its language
+const minScriptLetters = 10
+
+// minStopwords is how many common words of a language are needed to pick it
+const minStopwords = 5
+
+// scriptLanguages maps writing systems to the language most likely using them
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "Korean"},
+	{unicode.Cyrillic, "Russian"},
+	{unicode.Arabic, "Arabic"},
+	{unicode.Greek, "Greek"},
+	{unicode.Hebrew, "Hebrew"},
+	{unicode.Thai, "Thai"},
+	{unicode.Devanagari, "Hindi"},
+	{unicode.Han, "Chinese"},
+}
+
+// stopwords are frequent words that tell apart languages written in the Latin script
+var stopwords = map[string][]string{
+	"English":    {"the", "and", "is", "this", "that", "for", "with", "not", "are", "when"},
+	"German":     {"der", "die", "das", "und", "ist", "nicht", "mit", "für", "wenn", "auch"},
+	"French":     {"le", "la", "les", "et", "est", "des", "pour", "une", "pas", "avec"},
+	"Spanish":    {"el", "los", "las", "y", "es", "para", "una", "con", "por", "que"},
+	"Portuguese": {"o", "os", "as", "e", "é", "para", "uma", "com", "não", "que"},
+	"Italian":    {"il", "gli", "e", "è", "per", "una", "con", "non", "che", "della"},
+	"Dutch":      {"de", "het", "en", "is", "een", "niet", "met", "voor", "van", "dat"},
+	"Indonesian": {"yang", "dan", "ini", "untuk", "dengan", "tidak", "dari", "akan", "ada", "jika"},
+}
+
+// commentMarkers start a comment in common languages
+var commentMarkers = []string{"//", "/*", "#", "<!--"}
+
+// extensionCommentMarkers start a comment only in the languages of these file
+// extensions, since elsewhere they are common in code, e.g. ";" ending a
+// statement or "--" decrementing
+var extensionCommentMarkers = map[string][]string{
+	".sql": {"--"}, ".lua": {"--"}, ".hs": {"--"}, ".elm": {"--"}, ".ada": {"--"}, ".adb": {"--"}, ".ads": {"--"},
+	".lisp": {";"}, ".el": {";"}, ".clj": {";"}, ".cljs": {";"}, ".scm": {";"}, ".rkt": {";"}, ".asm": {";"}, ".s": {";"}, ".ini": {";"},
+}
+
+// DetectLanguage guesses the natural language of the comments added in a
+// diff, returning English when there isn't enough text to tell
+func DetectLanguage(diff string) string {
+	var text strings.Builder
+	for _, line := range addedLines(diff) {
+		text.WriteString(commentText(line.Text, line.Path))
+		text.WriteString("\n")
+	}
+	comments := text.String()
+
+	// Other scripts are recognized by their letters alone
+	best, bestCount := "", 0
+	kana := 0
+	for _, r := range comments {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			kana++
+		}
+	}
+	for _, script := range scriptLanguages {
+		count := 0
+		for _, r := range comments {
+			if unicode.Is(script.table, r) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = script.language, count
+		}
+	}
+	if kana >= minScriptLetters || (kana > 0 && best == "Chinese" && kana+bestCount >= minScriptLetters) {
+		return "Japanese"
+	}
+	if bestCount >= minScriptLetters {
+		return best
+	}
+
+	// Count the common words of each language written in the Latin script
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(comments), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for language, words := range stopwords {
+			for _, stopword := range words {
+				if word == stopword {
+					counts[language]++
+				}
+			}
+		}
+	}
+
+	best, bestCount = "English", 0
+	second := 0
+	for language, count := range counts {
+		if count > bestCount {
+			best, bestCount, second = language, count, bestCount
+		} else if count > second {
+			second = count
+		}
+	}
+	// Shared words like "e" or "que" make close counts unreliable
+	if bestCount < minStopwords || bestCount*2 < second*3 {
+		return "English"
+	}
+	return best
+}
+
+// commentText returns the comment part of a line of code in the file at
+// path, or the line itself if it is a continuation of a block comment
+func commentText(line, path string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "*") {
+		return strings.TrimPrefix(trimmed, "*")
+	}
+
+	markers := append(append([]string{}, commentMarkers...), extensionCommentMarkers[strings.ToLower(filepath.Ext(path))]...)
+	start, end := -1, -1
+	for _, marker := range markers {
+		if i := strings.Index(line, marker); i != -1 && (start == -1 || i < start) {
+			start, end = i, i+len(marker)
+		}
+	}
+	if start == -1 {
+		return ""
+	}
+	return line[end:]
+}
+
+// LanguageInstruction returns the prompt instruction to write the message in language
+func LanguageInstruction(language string) string {
+	return fmt.Sprintf("Write the commit message in %s.", language)
+}
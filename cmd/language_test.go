@@ -0,0 +1,42 @@
+package cmd
+
+import "testing"
+
+func TestCommentText(t *testing.T) {
+	tests := []struct {
+		line, path, want string
+	}{
+		{"x := 1 // compte les lignes", "main.go", " compte les lignes"},
+		{"# zählt die Zeilen", "run.py", " zählt die Zeilen"},
+		{" * continues the block", "main.c", " continues the block"},
+		{"i--;", "main.c", ""},
+		{"let total = a - -b;", "main.js", ""},
+		{"SELECT 1; -- cuenta las filas", "query.sql", " cuenta las filas"},
+		{"local n = 0 -- conta le righe", "init.lua", " conta le righe"},
+		{"(setq n 0) ; compte les lignes", "init.el", " compte les lignes"},
+		{"count = 0 ; not a comment", "app.rb", ""},
+	}
+	for _, tt := range tests {
+		if got := commentText(tt.line, tt.path); got != tt.want {
+			t.Errorf("commentText(%q, %q) = %q, want %q", tt.line, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguageIgnoresCode(t *testing.T) {
+	// Spanish words in code after ";" or "--" aren't comments outside SQL
+	diff := "+++ b/main.c\n@@ -1,0 +1,3 @@\n" +
+		"+i--; que de la el en los del se las por\n" +
+		"+x = y; que de la el en los del se las por\n" +
+		"+// count the lines of the file and return them\n"
+	if got := DetectLanguage(diff); got != "English" {
+		t.Errorf("DetectLanguage() = %q, want English", got)
+	}
+
+	sql := "+++ b/query.sql\n@@ -1,0 +1,2 @@\n" +
+		"+SELECT 1; -- que de la el en los del se las por\n" +
+		"+SELECT 2; -- cuenta las filas de la tabla y las devuelve por usuario\n"
+	if got := DetectLanguage(sql); got != "Spanish" {
+		t.Errorf("DetectLanguage() = %q, want Spanish", got)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// minLeakWords is the minimum number of words an instruction fragment needs
+// before it is matched, so short phrases don't cause false positives
+const minLeakWords = 3
+
+// quotedText matches double-quoted text in the template, such as the author's
+// -hint or example subjects, which the message may repeat on purpose
+var quotedText = regexp.MustCompile(`"(?:[^"\\\n]|\\.)*"`)
+
+// StripPromptLeaks removes lines of the message that echo instructions from
+// the prompt template, as small models sometimes do. It reports whether any
+// leaked instructions were found.
+func StripPromptLeaks(message, promptTemplate string) (string, bool) {
+	fragments := instructionFragments(promptTemplate)
+	if len(fragments) == 0 {
+		return message, false
+	}
+
+	var kept []string
+	leaked := false
+	for _, line := range strings.Split(message, "\n") {
+		// Pad with spaces so fragments only match on word boundaries
+		normalized := " " + normalizeText(line) + " "
+		isLeak := false
+		for _, fragment := range fragments {
+			if strings.Contains(normalized, " "+fragment+" ") {
+				isLeak = true
+				break
+			}
+		}
+
+		if isLeak {
+			leaked = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if !leaked {
+		return message, false
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), true
+}
+
+// instructionFragments splits the instruction part of the template into
+// normalized clauses that are long enough to be recognizable
+func instructionFragments(promptTemplate string) []string {
+	// Only the text before the diff placeholder holds instructions
+	if i := strings.Index(promptTemplate, "%s"); i != -1 {
+		promptTemplate = promptTemplate[:i]
+	}
+	promptTemplate = quotedText.ReplaceAllString(promptTemplate, "\n")
+
+	clauses := strings.FieldsFunc(promptTemplate, func(r rune) bool {
+		return r == '\n' || r == '.' || r == ',' || r == ':' || r == ';'
+	})
+
+	var fragments []string
+	for _, clause := range clauses {
+		normalized := normalizeText(clause)
+		if len(strings.Fields(normalized)) >= minLeakWords {
+			fragments = append(fragments, normalized)
+		}
+	}
+	return fragments
+}
+
+// normalizeText lowercases s and collapses punctuation and whitespace into single spaces
+func normalizeText(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	return strings.Join(words, " ")
+}
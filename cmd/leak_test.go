@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestStripPromptLeaks(t *testing.T) {
+	template := DefaultConfig().PromptTemplate
+
+	message := "Add login form\n\nGenerate a concise and descriptive git commit message based on the following changes"
+	if got, leaked := StripPromptLeaks(message, template); !leaked || got != "Add login form" {
+		t.Errorf("StripPromptLeaks() = %q, %v; want the echoed instruction removed", got, leaked)
+	}
+
+	if got, leaked := StripPromptLeaks("Add login form", template); leaked || got != "Add login form" {
+		t.Errorf("StripPromptLeaks() = %q, %v; want the message unchanged", got, leaked)
+	}
+}
+
+func TestStripPromptLeaksKeepsHint(t *testing.T) {
+	template := WithInstruction(DefaultConfig().PromptTemplate, IntentHint("fix the token refresh race"))
+
+	for _, message := range []string{
+		"Fix the token refresh race\n\nRefresh under a lock.",
+		"Fix the token refresh race",
+	} {
+		if got, leaked := StripPromptLeaks(message, template); leaked || got != message {
+			t.Errorf("StripPromptLeaks(%q) = %q, %v; want the message repeating the hint kept", message, got, leaked)
+		}
+	}
+}
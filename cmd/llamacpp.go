@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// llamaCppAPIURL is the native completion endpoint of llama-server on its default port
+const llamaCppAPIURL = "http://localhost:8080/completion"
+
+func init() {
+	RegisterProvider("llamacpp", newLlamaCppProvider, func(apiURL *url.URL) bool {
+		return strings.HasSuffix(strings.TrimSuffix(apiURL.Path, "/"), "/completion")
+	})
+}
+
+// llamaCppProvider generates text with the native /completion endpoint of
+// llama.cpp's llama-server
+type llamaCppProvider struct {
+	client *Client
+	apiURL string
+}
+
+// newLlamaCppProvider returns a llama.cpp provider, sending requests to
+// llama-server's default port unless another API URL is configured. A base
+// URL like http://localhost:8080 is completed with /completion.
+func newLlamaCppProvider(client *Client, apiURL string) Provider {
+	apiURL = strings.TrimSuffix(orDefaultURL(apiURL, llamaCppAPIURL), "/")
+	if !strings.HasSuffix(apiURL, "/completion") {
+		apiURL += "/completion"
+	}
+	return llamaCppProvider{client: client, apiURL: apiURL}
+}
+
+// llamaCppRequest is a /completion request. The prompt is cached so retries
+// and follow-up prompts only evaluate what changed.
+type llamaCppRequest struct {
+	Prompt      string          `json:"prompt"`
+	NPredict    *int            `json:"n_predict,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
+	Seed        *int            `json:"seed,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+	CachePrompt bool            `json:"cache_prompt"`
+	JSONSchema  json.RawMessage `json:"json_schema,omitempty"`
+	Stream      bool            `json:"stream"`
+}
+
+// llamaCppResponse is a /completion response
+type llamaCppResponse struct {
+	Content         string `json:"content"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+	TokensPredicted int    `json:"tokens_predicted"`
+}
+
+func (p llamaCppProvider) endpoint() string {
+	return p.apiURL
+}
+
+func (p llamaCppProvider) Name() string {
+	return "llamacpp"
+}
+
+// applyTemplate formats the prompt with the chat template of the loaded
+// model, so instruction-tuned models see a user turn. Servers without the
+// /apply-template endpoint get the prompt as it is.
+func (p llamaCppProvider) applyTemplate(ctx context.Context, text string) (string, error) {
+	reqBody, err := json.Marshal(map[string]any{"messages": []chatMessage{{Role: "user", Content: text}}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	bodyBytes, err := p.client.postRequest(ctx, strings.TrimSuffix(p.apiURL, "/completion")+"/apply-template", reqBody, nil)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return text, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var templated struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal(bodyBytes, &templated); err != nil || templated.Prompt == "" {
+		return text, nil
+	}
+	return templated.Prompt, nil
+}
+
+// Generate sends the prompt with num_predict as n_predict. Ollama's "json"
+// format becomes an empty schema, which llama-server takes as any JSON.
+func (p llamaCppProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	text, err := p.applyTemplate(ctx, prompt.Text)
+	if err != nil {
+		return "", err
+	}
+
+	req := llamaCppRequest{Prompt: text, CachePrompt: true}
+	if prompt.Options != nil {
+		req.NPredict = prompt.Options.MaxTokens
+		req.Temperature = prompt.Options.Temperature
+		req.TopP = prompt.Options.TopP
+		req.Seed = prompt.Options.Seed
+		req.Stop = prompt.Options.Stop
+	}
+	if len(prompt.Format) > 0 {
+		var mode string
+		if json.Unmarshal(prompt.Format, &mode) == nil && mode == "json" {
+			req.JSONSchema = json.RawMessage(`{}`)
+		} else {
+			req.JSONSchema = prompt.Format
+		}
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	bodyBytes, err := p.client.postRequest(ctx, p.apiURL, reqBody, nil)
+	if err != nil {
+		return "", err
+	}
+	var resp llamaCppResponse
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	p.client.reportUsage(Usage{PromptTokens: resp.TokensEvaluated, CompletionTokens: resp.TokensPredicted})
+	if strings.TrimSpace(resp.Content) == "" {
+		return "", fmt.Errorf("%w; check the URL", ErrEmptyResponse)
+	}
+	return resp.Content, nil
+}
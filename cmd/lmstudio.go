@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// lmStudioAPIURL is the chat completions endpoint of LM Studio's local server
+const lmStudioAPIURL = "http://localhost:1234/v1/chat/completions"
+
+func init() {
+	RegisterProvider("lmstudio", newLMStudioProvider, func(apiURL *url.URL) bool {
+		return apiURL.Port() == "1234" && IsLocalURL(apiURL.String())
+	})
+}
+
+// lmStudioProvider generates text with the OpenAI-compatible server of LM Studio
+type lmStudioProvider struct {
+	client *Client
+	apiURL string
+}
+
+// newLMStudioProvider returns an LM Studio provider, sending requests to
+// LM Studio's default port unless another API URL is configured. A base URL
+// like http://localhost:1234/v1 is completed with /chat/completions.
+func newLMStudioProvider(client *Client, apiURL string) Provider {
+	apiURL = orDefaultURL(apiURL, lmStudioAPIURL)
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), chatCompletionsPath) {
+		apiURL = strings.TrimSuffix(apiURL, "/") + chatCompletionsPath
+	}
+	return lmStudioProvider{client: client, apiURL: apiURL}
+}
+
+func (p lmStudioProvider) endpoint() string {
+	return p.apiURL
+}
+
+func (p lmStudioProvider) Name() string {
+	return "lmstudio"
+}
+
+func (p lmStudioProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	text, err := p.client.sendChatRequest(ctx, p.apiURL, http.Header{}, prompt)
+	if err != nil {
+		return "", p.explain(err, prompt.Model)
+	}
+	return text, nil
+}
+
+// explain turns LM Studio's errors for a stopped server or an unloaded model
+// into messages saying what to do
+func (p lmStudioProvider) explain(err error, model string) error {
+	if errors.Is(err, ErrAPIUnreachable) {
+		return fmt.Errorf("%w; start LM Studio's server in its Developer tab or with 'lms server start'", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return err
+	}
+	body := strings.ToLower(statusErr.Body)
+	switch {
+	case strings.Contains(body, "no models loaded"):
+		return fmt.Errorf("%w: LM Studio has no model loaded; load one in the app or with 'lms load <model>'", ErrModelNotFound)
+	case strings.Contains(body, "model") && (strings.Contains(body, "not found") || strings.Contains(body, "not loaded") || strings.Contains(body, "invalid")):
+		return fmt.Errorf("%w: %s is not loaded in LM Studio; load it with 'lms load %s' or pick one from 'ollama-commit models'", ErrModelNotFound, model, model)
+	}
+	return err
+}
+
+// listModels returns the models LM Studio has loaded
+func (p lmStudioProvider) listModels() ([]ModelInfo, error) {
+	endpoint := strings.TrimSuffix(strings.TrimSuffix(p.apiURL, "/"), chatCompletionsPath) + "/models"
+	resp, err := p.client.http.Get(endpoint)
+	if err != nil {
+		return nil, p.explain(fmt.Errorf("%w at %s: %w", ErrAPIUnreachable, endpoint, err), "")
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, model := range list.Data {
+		models = append(models, ModelInfo{Name: model.ID})
+	}
+	return models, nil
+}
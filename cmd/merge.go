@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeInProgress reports whether a merge is waiting to be committed
+func MergeInProgress() bool {
+	return gitCommand("rev-parse", "--quiet", "--verify", "MERGE_HEAD").Run() == nil
+}
+
+// MergeContext describes the branch being merged and the commits it brings
+// in, to put in front of the diff. It is empty if the commits can't be listed.
+func MergeContext() string {
+	commits, err := gitCommand("log", "--reverse", "--no-merges", "--format=- %s", "HEAD..MERGE_HEAD").Output()
+	if err != nil || len(strings.TrimSpace(string(commits))) == 0 {
+		return ""
+	}
+
+	branch := "MERGE_HEAD"
+	if output, err := gitCommand("name-rev", "--name-only", "--exclude=tags/*", "MERGE_HEAD").Output(); err == nil {
+		if name := strings.TrimSpace(string(output)); name != "" && name != "undefined" {
+			branch = name
+		}
+	}
+	return fmt.Sprintf("Merging %s, which brings in these commits:\n%s\n\n", branch, strings.TrimSpace(string(commits)))
+}
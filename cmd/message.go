@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bulletLine matches a body line starting a bullet point: "- ", "* " or "1. "
+var bulletLine = regexp.MustCompile(`^\s*([-*]|\d+\.)\s`)
+
+// Subject separator modes
+const (
+	SeparatorBlankLine = "blank-line" // Subject is everything before the first blank line, as git does
+	SeparatorNewline   = "newline"    // Subject is the first line even without a blank line after it
+)
+
+// Line ending styles
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+	LineEndingAuto = "auto" // CRLF if core.autocrlf is true, LF otherwise
+)
+
+// ResolveLineEnding turns the configured style into lf or crlf, reading
+// core.autocrlf for the auto style
+func ResolveLineEnding(style string) string {
+	if style != LineEndingAuto {
+		if style == LineEndingCRLF {
+			return LineEndingCRLF
+		}
+		return LineEndingLF
+	}
+
+	output, err := gitCommand("config", "--get", "core.autocrlf").Output()
+	if err == nil && strings.TrimSpace(string(output)) == "true" {
+		return LineEndingCRLF
+	}
+	return LineEndingLF
+}
+
+// ApplyLineEnding converts the line endings of message to the resolved style
+func ApplyLineEnding(message, style string) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	if style == LineEndingCRLF {
+		return strings.ReplaceAll(message, "\n", "\r\n")
+	}
+	return message
+}
+
+// SplitMessage splits a commit message into subject and body. With the
+// blank-line mode the subject ends at the first blank line; with the newline
+// mode it ends at the first blank line, or the first newline if there is none.
+func SplitMessage(message, mode string) (subject, body string) {
+	message = strings.Trim(message, "\n")
+
+	if i := strings.Index(message, "\n\n"); i != -1 {
+		return strings.TrimSpace(message[:i]), strings.Trim(message[i+2:], "\n")
+	}
+	if mode == SeparatorNewline {
+		if i := strings.Index(message, "\n"); i != -1 {
+			return strings.TrimSpace(message[:i]), strings.Trim(message[i+1:], "\n")
+		}
+	}
+	return strings.TrimSpace(message), ""
+}
+
+// JoinMessage assembles a commit message from subject and body
+func JoinMessage(subject, body string) string {
+	if body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// BulletLimitInstruction returns the prompt instruction capping the number of body bullet points
+func BulletLimitInstruction(max int) string {
+	return fmt.Sprintf("If the commit message body uses bullet points, use at most %d of them.", max)
+}
+
+// LimitBodyBullets drops the bullet points after the first max ones in the
+// message body, along with their indented continuation lines. It reports
+// whether any were dropped.
+func LimitBodyBullets(message string, max int) (string, bool) {
+	if max <= 0 {
+		return message, false
+	}
+
+	subject, body := SplitMessage(message, SeparatorBlankLine)
+	if body == "" {
+		return message, false
+	}
+
+	var kept []string
+	bullets := 0
+	dropping := false
+	for _, line := range strings.Split(body, "\n") {
+		switch {
+		case bulletLine.MatchString(line):
+			bullets++
+			dropping = bullets > max
+		case dropping && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")):
+			// Continuation of a dropped bullet
+		default:
+			dropping = false
+		}
+
+		if !dropping {
+			kept = append(kept, line)
+		}
+	}
+
+	if bullets <= max {
+		return message, false
+	}
+	return JoinMessage(subject, strings.TrimRight(strings.Join(kept, "\n"), "\n")), true
+}
+
+// minSubjectBytes is the shortest subject LimitMessageBytes cuts a subject to
+const minSubjectBytes = 20
+
+// LimitMessageBytes shortens a message longer than max bytes by dropping body
+// paragraphs from the end, then cutting the subject at a word boundary. The
+// trailing footer block is always kept. The size is measured as git stores the
+// message, with the given line ending and the newline git adds at the end. It
+// reports whether anything was dropped, and returns an error if the footers
+// leave no room for a subject.
+func LimitMessageBytes(message string, max int, lineEnding string) (string, bool, error) {
+	size := func(message string) int {
+		return len(ApplyLineEnding(message+"\n", lineEnding))
+	}
+	if max <= 0 || size(message) <= max {
+		return message, false, nil
+	}
+
+	subject, body := SplitMessage(message, SeparatorBlankLine)
+	paragraphs := strings.Split(body, "\n\n")
+	footer := ""
+	if body != "" && isFooterBlock(paragraphs[len(paragraphs)-1]) {
+		footer = paragraphs[len(paragraphs)-1]
+		paragraphs = paragraphs[:len(paragraphs)-1]
+	}
+	join := func(subject string, paragraphs []string) string {
+		if footer != "" {
+			paragraphs = append(append([]string{}, paragraphs...), footer)
+		}
+		return JoinMessage(subject, strings.Join(paragraphs, "\n\n"))
+	}
+
+	for len(paragraphs) > 0 {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+		if shortened := join(subject, paragraphs); size(shortened) <= max {
+			return shortened, true, nil
+		}
+	}
+
+	// Cut the subject to whatever room the footers leave
+	room := max - size(join("", nil))
+	if room < minSubjectBytes {
+		return message, false, fmt.Errorf("the footers alone take %d of the %d bytes allowed by maxMessageBytes, leaving no room for a subject", size(join("", nil)), max)
+	}
+	return join(cutSubject(subject, room), nil), true, nil
+}
+
+// cutSubject shortens a subject to at most max bytes, at the last space that
+// fits or else at a character boundary
+func cutSubject(subject string, max int) string {
+	if len(subject) <= max {
+		return subject
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(subject[cut]) {
+		cut--
+	}
+	if subject[cut] != ' ' {
+		if space := strings.LastIndex(subject[:cut], " "); space > 0 {
+			cut = space
+		}
+	}
+	return strings.TrimRight(subject[:cut], " ")
+}
+
+// Subject case styles
+const (
+	SubjectCasePreserve = "preserve"
+	SubjectCaseSentence = "sentence" // "fix: Add retry"
+	SubjectCaseLower    = "lower"    // "fix: add retry"
+)
+
+// conventionalTypes are the commit types of the Conventional Commits spec
+// and the Angular convention it grew out of
+var conventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// subjectPrefix matches a conventional commit type with optional scope, or a
+// gitmoji shortcode, before the description. Other words followed by a colon,
+// like "Note:" or "API:", are part of the description.
+var subjectPrefix = regexp.MustCompile(`^((?i:` + strings.Join(conventionalTypes, "|") + `)(\([^)]*\))?!?: +|:[a-z0-9_+-]+: *)+`)
+
+// ApplySubjectCase changes the case of the first letter of the subject's
+// description, after any type prefix. Words in all caps like "API" are left
+// alone. The body is never changed.
+func ApplySubjectCase(message, style string) string {
+	if style != SubjectCaseSentence && style != SubjectCaseLower {
+		return message
+	}
+
+	subject, rest, _ := strings.Cut(message, "\n")
+	prefix := subjectPrefix.FindString(subject)
+	runes := []rune(subject[len(prefix):])
+
+	// Skip emoji and punctuation before the first word
+	i := 0
+	for i < len(runes) && !unicode.IsLetter(runes[i]) {
+		i++
+	}
+	if i == len(runes) {
+		return message
+	}
+
+	if style == SubjectCaseSentence {
+		runes[i] = unicode.ToUpper(runes[i])
+	} else if i+1 >= len(runes) || !unicode.IsUpper(runes[i+1]) {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	subject = prefix + string(runes)
+	if strings.Contains(message, "\n") {
+		return subject + "\n" + rest
+	}
+	return subject
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import "testing"
+
+func TestApplyLineEnding(t *testing.T) {
+	tests := []struct {
+		message, style, want string
+	}{
+		{"Add two\n\nBody", LineEndingLF, "Add two\n\nBody"},
+		{"Add two\r\n\r\nBody", LineEndingLF, "Add two\n\nBody"},
+		{"Add two\n\nBody", LineEndingCRLF, "Add two\r\n\r\nBody"},
+		{"Add two\r\n\nBody", LineEndingCRLF, "Add two\r\n\r\nBody"},
+	}
+	for _, tt := range tests {
+		if got := ApplyLineEnding(tt.message, tt.style); got != tt.want {
+			t.Errorf("ApplyLineEnding(%q, %q) = %q, want %q", tt.message, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLineEnding(t *testing.T) {
+	dir := newTestRepo(t)
+	chdir(t, dir)
+
+	tests := []struct {
+		style, autocrlf, want string
+	}{
+		{"", "true", LineEndingLF},
+		{LineEndingLF, "true", LineEndingLF},
+		{LineEndingCRLF, "false", LineEndingCRLF},
+		{LineEndingAuto, "true", LineEndingCRLF},
+		{LineEndingAuto, "input", LineEndingLF},
+		{LineEndingAuto, "false", LineEndingLF},
+	}
+	for _, tt := range tests {
+		runGit(t, dir, "config", "core.autocrlf", tt.autocrlf)
+		if got := ResolveLineEnding(tt.style); got != tt.want {
+			t.Errorf("ResolveLineEnding(%q) with core.autocrlf=%s = %q, want %q", tt.style, tt.autocrlf, got, tt.want)
+		}
+	}
+}
+
+func TestLimitBodyBullets(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		max           int
+		want          string
+		wantTruncated bool
+	}{
+		{
+			name:    "unlimited",
+			message: "Add login\n\n- one\n- two\n- three",
+			max:     0,
+			want:    "Add login\n\n- one\n- two\n- three",
+		},
+		{
+			name:    "within the limit",
+			message: "Add login\n\n- one\n- two",
+			max:     2,
+			want:    "Add login\n\n- one\n- two",
+		},
+		{
+			name:          "dash, star and numbered bullets",
+			message:       "Add login\n\n- one\n* two\n3. three\n4. four",
+			max:           2,
+			want:          "Add login\n\n- one\n* two",
+			wantTruncated: true,
+		},
+		{
+			name:          "continuation lines of dropped bullets",
+			message:       "Add login\n\n- one\n  continued\n- two\n  continued too",
+			max:           1,
+			want:          "Add login\n\n- one\n  continued",
+			wantTruncated: true,
+		},
+		{
+			name:          "paragraph after the bullets is kept",
+			message:       "Add login\n\n- one\n- two\n\nRefs: ABC-1",
+			max:           1,
+			want:          "Add login\n\n- one\n\nRefs: ABC-1",
+			wantTruncated: true,
+		},
+		{
+			name:    "subject is never counted",
+			message: "- Add login\n\n- one",
+			max:     1,
+			want:    "- Add login\n\n- one",
+		},
+		{
+			name:    "no body",
+			message: "Add login",
+			max:     1,
+			want:    "Add login",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := LimitBodyBullets(tt.message, tt.max)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("LimitBodyBullets() = %q, %v, want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestApplySubjectCase(t *testing.T) {
+	tests := []struct {
+		message string
+		style   string
+		want    string
+	}{
+		{"fix: Add retry", SubjectCaseLower, "fix: add retry"},
+		{"feat(api)!: add login\n\nBody stays As is", SubjectCaseSentence, "feat(api)!: Add login\n\nBody stays As is"},
+		{"Fix: Add retry", SubjectCaseLower, "Fix: add retry"},
+		{":sparkles: add login", SubjectCaseSentence, ":sparkles: Add login"},
+		{"API: document the limits", SubjectCaseSentence, "API: document the limits"},
+		{"Note: this changes defaults", SubjectCaseLower, "note: this changes defaults"},
+		{"update: Bump version", SubjectCaseLower, "update: Bump version"},
+		{"Add retry", SubjectCasePreserve, "Add retry"},
+	}
+	for _, tt := range tests {
+		if got := ApplySubjectCase(tt.message, tt.style); got != tt.want {
+			t.Errorf("ApplySubjectCase(%q, %q) = %q, want %q", tt.message, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestLimitMessageBytes(t *testing.T) {
+	footer := "Refs: ABC-1\nCo-authored-by: Jane Doe <jane@example.com>"
+	message := "Add login page\n\nFirst paragraph explaining the change.\n\nSecond paragraph with more detail.\n\n" + footer
+
+	tests := []struct {
+		name       string
+		message    string
+		max        int
+		lineEnding string
+		want       string
+		wantErr    bool
+	}{
+		{"fits", message, len(message) + 1, LineEndingLF, message, false},
+		{"drops the last paragraph", message, len(message) - 10, LineEndingLF, "Add login page\n\nFirst paragraph explaining the change.\n\n" + footer, false},
+		{"keeps the footers over the body", message, len("Add login page\n\n"+footer) + 1, LineEndingLF, "Add login page\n\n" + footer, false},
+		{"cuts the subject", "Add a much longer subject than fits\n\n" + footer, len("\n\n"+footer) + 26, LineEndingLF, "Add a much longer subject\n\n" + footer, false},
+		{"cuts the subject between words", "Add a much longer subject than fits\n\n" + footer, len("\n\n"+footer) + 24, LineEndingLF, "Add a much longer\n\n" + footer, false},
+		{"counts carriage returns", message, len(message) + 1, LineEndingCRLF, "Add login page\n\nFirst paragraph explaining the change.\n\n" + footer, false},
+		{"no room for a subject", message, len(footer) + 10, LineEndingLF, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := LimitMessageBytes(tt.message, tt.max, tt.lineEnding)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LimitMessageBytes() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LimitMessageBytes() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LimitMessageBytes() = %q, want %q", got, tt.want)
+			}
+			if truncated != (got != tt.message) {
+				t.Errorf("truncated = %v", truncated)
+			}
+			if stored := len(ApplyLineEnding(got+"\n", tt.lineEnding)); stored > tt.max {
+				t.Errorf("stored message is %d bytes, want at most %d", stored, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mistralAPIURL is Mistral's chat completions endpoint
+const mistralAPIURL = "https://api.mistral.ai/v1/chat/completions"
+
+func init() {
+	RegisterProvider("mistral", newMistralProvider, func(apiURL *url.URL) bool {
+		return apiURL.Hostname() == "api.mistral.ai"
+	})
+}
+
+// mistralProvider generates text with Mistral's chat completions API
+type mistralProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newMistralProvider returns a Mistral provider, sending requests to Mistral's
+// endpoint unless another API URL is configured. A base URL is completed with
+// /chat/completions. The API key is read from OLLAMA_COMMIT_API_KEY,
+// MISTRAL_API_KEY or apiKeys.mistral.
+func newMistralProvider(client *Client, apiURL string) Provider {
+	apiURL = orDefaultURL(apiURL, mistralAPIURL)
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), chatCompletionsPath) {
+		apiURL = strings.TrimSuffix(apiURL, "/") + chatCompletionsPath
+	}
+	return mistralProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("mistral", "MISTRAL_API_KEY", apiURL, "api.mistral.ai")}
+}
+
+// mistralRequest is a Mistral chat completions request. Mistral rejects
+// unknown fields, and takes the seed as random_seed.
+type mistralRequest struct {
+	chatRequest
+	RandomSeed *int `json:"random_seed,omitempty"`
+}
+
+func (p mistralProvider) endpoint() string {
+	return p.apiURL
+}
+
+func (p mistralProvider) Name() string {
+	return "mistral"
+}
+
+// Generate sends the prompt with max_tokens and stop taken from the shared
+// options, like the other chat completions providers
+func (p mistralProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the mistral provider needs an API key; set MISTRAL_API_KEY or apiKeys.mistral")
+	}
+	req, err := chatRequestFor(prompt)
+	if err != nil {
+		return "", err
+	}
+	seed := req.Seed
+	req.Seed = nil
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.apiKey)
+	return p.client.postChatRequest(ctx, p.apiURL, header, mistralRequest{chatRequest: req, RandomSeed: seed})
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ModelInfo describes a model installed on the Ollama server
+type ModelInfo struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Details struct {
+		Family        string `json:"family"`
+		ParameterSize string `json:"parameter_size"`
+	} `json:"details"`
+}
+
+// tagsURL derives the /api/tags endpoint from the configured generate URL
+func tagsURL(apiURL string) (string, error) {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid Ollama API URL %q", apiURL)
+	}
+	parsed.Path = "/api/tags"
+	parsed.RawQuery = ""
+	return parsed.String(), nil
+}
+
+// ErrCannotListModels is returned when listing the models of a provider that
+// has no API for it
+var ErrCannotListModels = errors.New("the provider can't list its models")
+
+// modelLister is implemented by providers that can list their models
+type modelLister interface {
+	listModels() ([]ModelInfo, error)
+}
+
+// CanListModels reports whether ListModels works for provider
+func CanListModels(provider Provider) bool {
+	_, ok := provider.(modelLister)
+	return ok
+}
+
+// ListModels returns the models provider offers, or ErrCannotListModels if
+// it can't list them
+func ListModels(provider Provider) ([]ModelInfo, error) {
+	lister, ok := provider.(modelLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCannotListModels, provider.Name())
+	}
+	return lister.listModels()
+}
+
+// listModels returns the models installed on the Ollama server
+func (p ollamaProvider) listModels() ([]ModelInfo, error) {
+	endpoint, err := tagsURL(p.apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.http.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w at %s: %w", ErrAPIUnreachable, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var tags struct {
+		Models []ModelInfo `json:"models"`
+	}
+	if err := json.Unmarshal(bodyBytes, &tags); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+	return tags.Models, nil
+}
+
+// HasModel reports whether name is in the model list. A name without a tag
+// matches the model's "latest" tag, as in Ollama itself.
+func HasModel(models []ModelInfo, name string) bool {
+	for _, model := range models {
+		if model.Name == name || model.Name == name+":latest" {
+			return true
+		}
+	}
+	return false
+}
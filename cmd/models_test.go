@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestListModels(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"llama3:latest"}]}`))
+	}))
+	defer server.Close()
+
+	ollama, err := NewProvider(NewClient(), "ollama", server.URL+"/api/generate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := ListModels(ollama)
+	if err != nil || !HasModel(models, "llama3") {
+		t.Errorf("ListModels(ollama) = %v, %v; want llama3", models, err)
+	}
+
+	// Providers without a listing API must not be sent Ollama's
+	requests.Store(0)
+	for _, name := range []string{"openai", "anthropic", "tgi", "llamacpp"} {
+		provider, err := NewProvider(NewClient(), name, server.URL+"/v1/chat/completions")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if CanListModels(provider) {
+			t.Errorf("CanListModels(%s) = true, want false", name)
+		}
+		if _, err := ListModels(provider); !errors.Is(err, ErrCannotListModels) {
+			t.Errorf("ListModels(%s) error = %v, want ErrCannotListModels", name, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests sent for providers that can't list models, want none", n)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GenerationNote records how a commit message was produced, attached to the
+// commit as a git note so the message itself stays clean
+type GenerationNote struct {
+	Tool               string `json:"tool"`
+	Model              string `json:"model,omitempty"`
+	PromptSHA256       string `json:"promptSha256,omitempty"` // Hash of the prompt template, identifying it without storing it
+	Offline            bool   `json:"offline,omitempty"`
+	ReusedLast         bool   `json:"reusedLast,omitempty"`
+	Refinements        int    `json:"refinements"`        // Refinement instructions given by the user
+	SubjectRegenerated bool   `json:"subjectRegenerated"` // The user asked for a new subject
+	GeneratedAt        string `json:"generatedAt"`
+}
+
+// NewGenerationNote returns a note for a message generated with model and promptTemplate
+func NewGenerationNote(model, promptTemplate string) GenerationNote {
+	sum := sha256.Sum256([]byte(promptTemplate))
+	return GenerationNote{
+		Tool:         "ollama-commit",
+		Model:        model,
+		PromptSHA256: hex.EncodeToString(sum[:]),
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// AddNote attaches the note as JSON to the HEAD commit, replacing any note it already has
+func AddNote(note GenerationNote) error {
+	data, err := json.Marshal(note)
+	if err != nil {
+		return fmt.Errorf("failed to create note: %w", err)
+	}
+
+	output, err := gitCommand("notes", "add", "-f", "-m", string(data), "HEAD").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to add git note: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// offlineNotice labels messages that were generated without a model
+const offlineNotice = "Generated by ollama-commit without AI (offline mode)"
+
+// GenerateOfflineMessage builds a simple deterministic commit message from
+// the changed files, for use when no model is reachable
+func GenerateOfflineMessage(changes []FileChange) string {
+	if len(changes) == 0 {
+		return "Update files\n\n" + offlineNotice
+	}
+
+	var subject string
+	if len(changes) == 1 {
+		subject = describeChange(changes[0])
+	} else {
+		subject = summarizeChanges(changes)
+	}
+
+	var body strings.Builder
+	for _, change := range changes {
+		body.WriteString("- " + describeChange(change) + "\n")
+	}
+	return subject + "\n\n" + body.String() + "\n" + offlineNotice
+}
+
+// describeChange describes a single file change, e.g. "Add cmd/offline.go"
+func describeChange(change FileChange) string {
+	switch change.Status {
+	case 'A', 'C':
+		return "Add " + change.Path
+	case 'D':
+		return "Remove " + change.Path
+	case 'R':
+		return "Rename " + change.OldPath + " to " + change.Path
+	default:
+		return "Update " + change.Path
+	}
+}
+
+// summarizeChanges describes several file changes, e.g. "Update 3 files in src/, add tests"
+func summarizeChanges(changes []FileChange) string {
+	var files, tests []FileChange
+	for _, change := range changes {
+		if isTestFile(change.Path) {
+			tests = append(tests, change)
+		} else {
+			files = append(files, change)
+		}
+	}
+
+	// Only tests changed
+	if len(files) == 0 {
+		return changeVerb(tests) + " " + countFiles(len(tests), "test file") + inDirectory(tests)
+	}
+
+	subject := changeVerb(files) + " " + countFiles(len(files), "file") + inDirectory(files)
+	if len(tests) > 0 {
+		subject += ", " + strings.ToLower(changeVerb(tests)) + " tests"
+	}
+	return subject
+}
+
+// changeVerb picks the verb that fits all the changes
+func changeVerb(changes []FileChange) string {
+	added, removed := 0, 0
+	for _, change := range changes {
+		switch change.Status {
+		case 'A', 'C':
+			added++
+		case 'D':
+			removed++
+		}
+	}
+
+	switch len(changes) {
+	case added:
+		return "Add"
+	case removed:
+		return "Remove"
+	default:
+		return "Update"
+	}
+}
+
+// countFiles formats a file count, e.g. "1 file" or "3 files"
+func countFiles(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// inDirectory returns " in <dir>/" if all changes share a directory
+func inDirectory(changes []FileChange) string {
+	dir := path.Dir(changes[0].Path)
+	for _, change := range changes[1:] {
+		for dir != "." && !strings.HasPrefix(change.Path, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." {
+		return ""
+	}
+	return " in " + dir + "/"
+}
+
+// isTestFile reports whether the path looks like a test file
+func isTestFile(file string) bool {
+	base := path.Base(file)
+	return strings.Contains(base, "_test.") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") ||
+		strings.HasPrefix(file, "test/") ||
+		strings.HasPrefix(file, "tests/") ||
+		strings.Contains(file, "/test/") ||
+		strings.Contains(file, "/tests/")
+}
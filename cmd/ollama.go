@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultResponseFields are the field names searched for the generated text
+// when a response has neither "response" nor "content"
+var DefaultResponseFields = []string{"response", "content", "text", "message"}
+
+// SetResponseFields sets the field names searched for the generated text, in order of preference
+func (c *Client) SetResponseFields(fields []string) {
+	if len(fields) == 0 {
+		fields = DefaultResponseFields
+	}
+	c.responseFields = fields
+}
+
+func init() {
+	RegisterProvider(DefaultProvider, func(client *Client, apiURL string) Provider { return ollamaProvider{client, apiURL} }, nil)
+}
+
+// ollamaProvider generates text with the Ollama generate API. It also reads
+// the responses of servers that only resemble it, finding the text in the
+// client's response fields.
+type ollamaProvider struct {
+	client *Client
+	apiURL string
+}
+
+func (p ollamaProvider) Name() string {
+	return DefaultProvider
+}
+
+func (p ollamaProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	text, _, err := p.GenerateConversation(ctx, prompt)
+	return text, err
+}
+
+// streams marks the Ollama provider as streaming when the client is set to
+func (p ollamaProvider) streams() {}
+
+// GenerateConversation returns the generated text along with the context
+// Ollama returns for a follow-up request
+func (p ollamaProvider) GenerateConversation(ctx context.Context, prompt Prompt) (string, []int, error) {
+	ollamaReq := OllamaRequest{
+		Model:   prompt.Model,
+		Prompt:  prompt.Text,
+		Stream:  false, // We want the complete response, not streamed
+		Format:  prompt.Format,
+		Options: prompt.Options,
+		Context: prompt.Context,
+	}
+	ollamaResp, bodyBytes, err := p.client.fetchResponse(ctx, p.apiURL, ollamaReq)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Report the token usage if the API returned it
+	p.client.reportUsage(ollamaResp.usage())
+
+	// Check which field has the content
+	var text string
+	if ollamaResp.Response != "" {
+		text = ollamaResp.Response
+	} else if ollamaResp.Content != "" {
+		text = ollamaResp.Content
+	} else {
+		// Look for the text in other fields, including nested ones like choices[].message.content
+		var decoded interface{}
+		if err := json.Unmarshal(bytes.TrimSpace(bodyBytes), &decoded); err == nil {
+			text = findResponseText(decoded, p.client.responseFields)
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", nil, fmt.Errorf("%w; check the URL or responseFields", ErrEmptyResponse)
+	}
+	return text, ollamaResp.Context, nil
+}
+
+// OllamaRequest represents a request to the Ollama API
+type OllamaRequest struct {
+	Model   string          `json:"model"`
+	Prompt  string          `json:"prompt"`
+	Stream  bool            `json:"stream"`
+	Format  json.RawMessage `json:"format,omitempty"` // "json" or a JSON schema for structured output
+	Options *Options        `json:"options,omitempty"`
+	Context []int           `json:"context,omitempty"` // Conversation state returned by a previous generation
+}
+
+// OllamaResponse represents a response from the Ollama API
+// The Ollama API might return the response in different formats
+// We'll handle multiple possible response structures
+type OllamaResponse struct {
+	Response string `json:"response"`
+	Content  string `json:"content"` // Some versions use content instead of response
+	Context  []int  `json:"context"` // Conversation state that can be sent with a follow-up request
+
+	// Token counts, from Ollama or an OpenAI-compatible usage object
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+	Usage           *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// fetchResponse makes a single request to the API, streamed if streaming is
+// enabled, and returns the parsed response along with the raw body
+func (c *Client) fetchResponse(ctx context.Context, apiURL string, ollamaReq OllamaRequest) (OllamaResponse, []byte, error) {
+	if c.idleTimeout > 0 {
+		ollamaResp, err := c.streamRequest(ctx, apiURL, ollamaReq)
+		return ollamaResp, nil, err
+	}
+
+	reqBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return OllamaResponse{}, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	bodyBytes, err := c.postRequest(ctx, apiURL, reqBody, nil)
+	if err != nil {
+		return OllamaResponse{}, nil, err
+	}
+
+	// For debugging
+	// fmt.Printf("Raw API Response: %s\n", string(bodyBytes))
+
+	// Parse response
+	ollamaResp, err := parseOllamaResponse(bodyBytes)
+	return ollamaResp, bodyBytes, err
+}
+
+// findResponseText searches a decoded JSON value for the first non-empty
+// string in one of the candidate fields. Candidate fields holding objects are
+// searched first, then any other nested objects and arrays. Error objects are
+// skipped so their message is never taken for generated text.
+func findResponseText(value interface{}, fields []string) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, failed := v["error"].(map[string]interface{}); failed {
+			v = withoutKey(v, "error")
+		}
+
+		// Direct string values first
+		for _, field := range fields {
+			if text, ok := v[field].(string); ok && strings.TrimSpace(text) != "" {
+				return text
+			}
+		}
+		// Then objects under candidate fields, e.g. message.content
+		for _, field := range fields {
+			if nested, ok := v[field]; ok {
+				if text := findResponseText(nested, fields); text != "" {
+					return text
+				}
+			}
+		}
+		// Then everything else, e.g. choices[0].message.content
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if text := findResponseText(v[key], fields); text != "" {
+				return text
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if text := findResponseText(item, fields); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// withoutKey returns a copy of m without key
+func withoutKey(m map[string]interface{}, key string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, value := range m {
+		if k != key {
+			copied[k] = value
+		}
+	}
+	return copied
+}
+
+// parseOllamaResponse decodes the response body, tolerating servers that
+// stream NDJSON chunks even though Stream was set to false
+func parseOllamaResponse(body []byte) (OllamaResponse, error) {
+	var ollamaResp OllamaResponse
+	body = bytes.TrimSpace(body)
+
+	// Several JSON objects on separate lines means the body was streamed
+	lines := bytes.Split(body, []byte("\n"))
+	if len(lines) > 1 && isCompleteJSON(lines[0]) {
+		var response, content strings.Builder
+		for _, line := range lines {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var chunk OllamaResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				return ollamaResp, fmt.Errorf("failed to parse streamed response chunk: %w", err)
+			}
+			response.WriteString(chunk.Response)
+			content.WriteString(chunk.Content)
+
+			// The final chunk carries the token counts and context
+			if chunk.PromptEvalCount > 0 || chunk.EvalCount > 0 {
+				ollamaResp.PromptEvalCount = chunk.PromptEvalCount
+				ollamaResp.EvalCount = chunk.EvalCount
+			}
+			if len(chunk.Context) > 0 {
+				ollamaResp.Context = chunk.Context
+			}
+		}
+		ollamaResp.Response = response.String()
+		ollamaResp.Content = content.String()
+		return ollamaResp, nil
+	}
+
+	// Make sure the body wasn't cut off before unmarshalling
+	if !isCompleteJSON(body) {
+		return ollamaResp, fmt.Errorf("incomplete response from Ollama API (%d bytes received)", len(body))
+	}
+
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return ollamaResp, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return ollamaResp, nil
+}
+
+// isCompleteJSON reports whether data holds a JSON object or array whose
+// braces and brackets are balanced, ignoring any inside string literals
+func isCompleteJSON(data []byte) bool {
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return false
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				// Anything after the closing brace means more than one value
+				return len(bytes.TrimSpace(data[i+1:])) == 0
+			}
+		}
+	}
+	return false
+}
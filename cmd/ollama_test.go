@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chunkedServer responds with the given pieces, flushing after each so the
+// body is sent with chunked transfer encoding and no Content-Length
+func chunkedServer(t *testing.T, pieces ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		flusher := w.(http.Flusher)
+		for _, piece := range pieces {
+			w.Write([]byte(piece))
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchResponseChunked(t *testing.T) {
+	tests := []struct {
+		name    string
+		pieces  []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "object split across chunks",
+			pieces: []string{`{"response":"Add chu`, `nked {reading}",`, `"eval_count":3}` + "\n"},
+			want:   "Add chunked {reading}",
+		},
+		{
+			name:   "NDJSON despite stream false",
+			pieces: []string{`{"response":"Fix "}` + "\n", `{"response":"parsing"}` + "\n", `{"response":"","done":true,"eval_count":2}` + "\n"},
+			want:   "Fix parsing",
+		},
+		{
+			name:    "truncated body",
+			pieces:  []string{`{"response":"Cut o`},
+			wantErr: "incomplete response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := chunkedServer(t, tt.pieces...)
+
+			resp, body, err := NewClient().fetchResponse(context.Background(), server.URL, OllamaRequest{Model: "m", Prompt: "p"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("fetchResponse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchResponse() error = %v", err)
+			}
+			if resp.Response != tt.want {
+				t.Errorf("Response = %q, want %q", resp.Response, tt.want)
+			}
+			if len(body) == 0 {
+				t.Error("raw body is empty")
+			}
+		})
+	}
+}
+
+func TestIsCompleteJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`{"a":"b"}`, true},
+		{`{"a":"}"}`, true},
+		{`{"a":"\"{"}`, true},
+		{`{"a":{"b":1}`, false},
+		{`{"a":1} {"b":2}`, false},
+		{`"text"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := isCompleteJSON([]byte(tt.data)); got != tt.want {
+			t.Errorf("isCompleteJSON(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestFindResponseText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"response field", `{"response":"Add login"}`, "Add login"},
+		{"OpenAI choices", `{"choices":[{"message":{"role":"assistant","content":"Add login"}}]}`, "Add login"},
+		{"error object", `{"error":{"message":"model overloaded","type":"server_error"}}`, ""},
+		{"error beside choices", `{"error":{"message":"partial failure"},"choices":[{"text":"Add login"}]}`, "Add login"},
+		{"nested error object", `{"data":{"error":{"message":"quota exceeded"}}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(tt.body), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if got := findResponseText(decoded, DefaultResponseFields); got != tt.want {
+				t.Errorf("findResponseText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// chatCompletionsPath is the endpoint of OpenAI-compatible chat APIs
+const chatCompletionsPath = "/chat/completions"
+
+func init() {
+	RegisterProvider("openai", newOpenAIProvider, func(apiURL *url.URL) bool {
+		return strings.HasSuffix(strings.TrimSuffix(apiURL.Path, "/"), chatCompletionsPath)
+	})
+}
+
+// openAIProvider generates text with an OpenAI-compatible chat completions
+// API, as served by OpenAI, vLLM, LocalAI and others
+type openAIProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newOpenAIProvider returns an OpenAI-compatible provider. A base URL like
+// https://api.openai.com/v1 is completed with /chat/completions. The API key
+// is read from OLLAMA_COMMIT_API_KEY or OPENAI_API_KEY; local servers
+// usually don't need one.
+func newOpenAIProvider(client *Client, apiURL string) Provider {
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), chatCompletionsPath) {
+		apiURL = strings.TrimSuffix(apiURL, "/") + chatCompletionsPath
+	}
+	return openAIProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("openai", "OPENAI_API_KEY", apiURL, "api.openai.com")}
+}
+
+// chatMessage is a message of a chat completions request or response
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is a chat completions request
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+}
+
+// chatResponse is a chat completions response
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// chatRequestFor builds the chat completions request for a prompt
+func chatRequestFor(prompt Prompt) (chatRequest, error) {
+	req := chatRequest{
+		Model:    prompt.Model,
+		Messages: []chatMessage{{Role: "user", Content: prompt.Text}},
+	}
+	if prompt.Options != nil {
+		req.MaxTokens = prompt.Options.MaxTokens
+		req.Temperature = prompt.Options.Temperature
+		req.TopP = prompt.Options.TopP
+		req.Seed = prompt.Options.Seed
+		req.Stop = prompt.Options.Stop
+	}
+
+	// Ollama's "json" format is JSON mode, anything else a schema
+	if len(prompt.Format) > 0 {
+		var mode string
+		if json.Unmarshal(prompt.Format, &mode) == nil && mode == "json" {
+			req.ResponseFormat = json.RawMessage(`{"type":"json_object"}`)
+		} else {
+			format, err := json.Marshal(map[string]any{
+				"type":        "json_schema",
+				"json_schema": map[string]any{"name": "commit", "schema": prompt.Format},
+			})
+			if err != nil {
+				return req, fmt.Errorf("failed to encode the response format: %w", err)
+			}
+			req.ResponseFormat = format
+		}
+	}
+	return req, nil
+}
+
+func (p openAIProvider) Name() string {
+	return "openai"
+}
+
+func (p openAIProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return p.client.sendChatRequest(ctx, p.apiURL, header, prompt)
+}
+
+// sendChatRequest sends the prompt to a chat completions endpoint and returns
+// the generated text
+func (c *Client) sendChatRequest(ctx context.Context, apiURL string, header http.Header, prompt Prompt) (string, error) {
+	req, err := chatRequestFor(prompt)
+	if err != nil {
+		return "", err
+	}
+	return c.postChatRequest(ctx, apiURL, header, req)
+}
+
+// postChatRequest posts a chat completions request, which may extend
+// chatRequest for APIs that differ from OpenAI's, and returns the generated text
+func (c *Client) postChatRequest(ctx context.Context, apiURL string, header http.Header, req any) (string, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	bodyBytes, err := c.postRequest(ctx, apiURL, reqBody, header)
+	if err != nil {
+		return "", err
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Usage != nil {
+		c.reportUsage(Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens})
+	}
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("%w; check the URL and model", ErrEmptyResponse)
+	}
+	return resp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OpenRouter attributes requests to the app named by these headers
+const (
+	openRouterReferer = "https://github.com/mrandiw/ollama-commit"
+	openRouterTitle   = "ollama-commit"
+)
+
+func init() {
+	RegisterProvider("openrouter", newOpenRouterProvider, func(apiURL *url.URL) bool {
+		return apiURL.Hostname() == "openrouter.ai"
+	})
+}
+
+// openRouterProvider generates text with OpenRouter's chat completions API.
+// Model slugs like meta-llama/llama-3.1-8b-instruct:free are sent unchanged.
+type openRouterProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newOpenRouterProvider returns an OpenRouter provider. A base URL like
+// https://openrouter.ai/api/v1 is completed with /chat/completions. The API
+// key is read from OLLAMA_COMMIT_API_KEY, OPENROUTER_API_KEY or
+// apiKeys.openrouter.
+func newOpenRouterProvider(client *Client, apiURL string) Provider {
+	if !strings.HasSuffix(strings.TrimSuffix(apiURL, "/"), chatCompletionsPath) {
+		apiURL = strings.TrimSuffix(apiURL, "/") + chatCompletionsPath
+	}
+	return openRouterProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("openrouter", "OPENROUTER_API_KEY", apiURL, "openrouter.ai")}
+}
+
+func (p openRouterProvider) Name() string {
+	return "openrouter"
+}
+
+func (p openRouterProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	if p.apiKey == "" {
+		return "", fmt.Errorf("the openrouter provider needs an API key; set OPENROUTER_API_KEY or apiKeys.openrouter")
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+p.apiKey)
+	header.Set("HTTP-Referer", openRouterReferer)
+	header.Set("X-Title", openRouterTitle)
+	return p.client.sendChatRequest(ctx, p.apiURL, header, prompt)
+}
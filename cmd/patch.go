@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadPatchFile reads a .patch or .diff file, such as one created by git
+// format-patch, so it can be described instead of the repository's changes
+func ReadPatchFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read patch file: %w", err)
+	}
+
+	patch := string(data)
+	if !isPatch(patch) {
+		return "", fmt.Errorf("%s doesn't look like a patch: no \"diff --git\" or ---/+++ headers found", path)
+	}
+	return patch, nil
+}
+
+// isPatch reports whether text contains a git diff header or a ---/+++ header pair
+func isPatch(text string) bool {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			return true
+		}
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchFiles returns the paths of the files changed by a patch, using the
+// old path for deleted files
+func PatchFiles(patch string) []string {
+	var files []string
+	var oldPath string
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldPath = patchPath(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			path := patchPath(line[4:], "b/")
+			if path == "/dev/null" {
+				path = oldPath
+			}
+			if path != "" && path != "/dev/null" {
+				files = append(files, path)
+			}
+		}
+	}
+	return files
+}
+
+// patchPath extracts the path from a ---/+++ header, dropping the a/ or b/
+// prefix and any trailing timestamp
+func patchPath(header, prefix string) string {
+	if i := strings.Index(header, "\t"); i != -1 {
+		header = header[:i]
+	}
+	return strings.TrimPrefix(strings.TrimSpace(header), prefix)
+}
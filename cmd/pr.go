@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// prBaseCandidates are tried in order when no base branch is given for a PR
+var prBaseCandidates = []string{"origin/HEAD", "origin/main", "origin/master", "main", "master"}
+
+// PRDescription is a generated pull request title and body
+type PRDescription struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// DefaultPRBase returns the first existing ref commonly used as the default branch
+func DefaultPRBase() (string, error) {
+	for _, ref := range prBaseCandidates {
+		if VerifyRef(ref) == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a base branch, use -pr-base to set one")
+}
+
+// GetBranchChanges returns the commit messages and the combined diff of the
+// current branch since it diverged from base
+func GetBranchChanges(base string, opts DiffOptions) (string, error) {
+	if err := VerifyRef(base); err != nil {
+		return "", err
+	}
+
+	output, err := gitOutput("", "merge-base", base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the merge base with %s: %w", base, err)
+	}
+	mergeBase := strings.TrimSpace(string(output))
+
+	commits, err := gitOutput("", "log", "--reverse", "--format=- %s%n%w(0,2,2)%b", mergeBase+"..HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch commits: %w", err)
+	}
+	if len(strings.TrimSpace(string(commits))) == 0 {
+		return "", nil
+	}
+
+	args := append([]string{"diff", "--no-color"}, opts.detectionArgs()...)
+	diff, err := gitOutput("", append(args, mergeBase, "HEAD")...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch diff: %w", err)
+	}
+
+	return "Commits:\n" + strings.TrimSpace(string(commits)) + "\n\nDiff:\n" + string(diff), nil
+}
+
+// prInstructions is appended to the PR prompt to request the JSON fields
+const prInstructions = `
+
+Respond with a JSON object with the following fields:
+- "title": a concise pull request title
+- "body": a markdown pull request description with a short summary followed by a checklist of the changes`
+
+// GeneratePRDescription asks the model for a pull request title and body
+// describing the branch changes
+func (c *Client) GeneratePRDescription(provider Provider, branchChanges, model, prTemplate string, options *Options) (PRDescription, error) {
+	var pr PRDescription
+
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(prTemplate, branchChanges) + prInstructions,
+		Format:  json.RawMessage(`"json"`),
+		Options: options,
+	}
+	output, err := c.sendPrompt(provider, prompt)
+	if err != nil {
+		return pr, err
+	}
+
+	// Use the first line as the title if the model didn't return JSON
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start || json.Unmarshal([]byte(output[start:end+1]), &pr) != nil || pr.Title == "" {
+		pr.Title, pr.Body = SplitMessage(output, SeparatorNewline)
+	}
+
+	pr.Title = strings.TrimSpace(pr.Title)
+	pr.Body = strings.TrimSpace(pr.Body)
+	return pr, nil
+}
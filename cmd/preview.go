@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxSubjectDisplay is the subject length beyond which many tools cut it off
+const maxSubjectDisplay = 72
+
+// gitDateFormat is the date format of git log's default output
+const gitDateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+// LogPreview renders the message the way git log --oneline and git log
+// would show it once committed. The commit hash is a placeholder.
+func LogPreview(message string) string {
+	subject, body := SplitMessage(message, SeparatorBlankLine)
+	// git log --oneline shows the subject paragraph on one line
+	oneline := strings.Join(strings.Fields(subject), " ")
+
+	var b strings.Builder
+	b.WriteString("git log --oneline:\n")
+	fmt.Fprintf(&b, "0000000 %s\n", oneline)
+	if len([]rune(oneline)) > maxSubjectDisplay {
+		fmt.Fprintf(&b, "        %s^ subject is %d characters, tools often cut it off after %d\n",
+			strings.Repeat(" ", maxSubjectDisplay), len([]rune(oneline)), maxSubjectDisplay)
+	}
+
+	b.WriteString("\ngit log:\n")
+	b.WriteString("commit " + strings.Repeat("0", 40) + "\n")
+	fmt.Fprintf(&b, "Author: %s <%s>\n", gitConfigValue("user.name"), gitConfigValue("user.email"))
+	fmt.Fprintf(&b, "Date:   %s\n\n", time.Now().Format(gitDateFormat))
+	for _, line := range strings.Split(JoinMessage(subject, body), "\n") {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString("    " + line + "\n")
+	}
+	return b.String()
+}
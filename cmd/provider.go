@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultProvider is the backend used when none is configured and the API URL
+// doesn't identify another
+const DefaultProvider = "ollama"
+
+// DefaultAPIURL is the API URL used when none is configured, a local Ollama server
+const DefaultAPIURL = "http://localhost:11434/api/generate"
+
+// Prompt is a single request for generated text, independent of the backend
+type Prompt struct {
+	Model   string
+	Text    string
+	Format  json.RawMessage // "json" or a JSON schema for structured output, nil for free text
+	Options *Options        // Model parameters, the server's defaults if nil
+	Context []int           // Conversation state returned by a previous generation, for providers that keep one
+}
+
+// Provider generates text with a model backend. Providers return the text as
+// the model produced it; retries and cleanup are shared by all of them.
+type Provider interface {
+	Name() string
+	Generate(ctx context.Context, prompt Prompt) (string, error)
+}
+
+// ConversationProvider is implemented by providers that return conversation
+// state, so a follow-up prompt doesn't need to repeat the previous one
+type ConversationProvider interface {
+	Provider
+	GenerateConversation(ctx context.Context, prompt Prompt) (string, []int, error)
+}
+
+// ProviderFactory creates a provider sending requests to apiURL with client
+type ProviderFactory func(client *Client, apiURL string) Provider
+
+// registeredProvider is a provider in the registry
+type registeredProvider struct {
+	factory ProviderFactory
+	detect  func(apiURL *url.URL) bool // Reports whether a URL belongs to the provider, nil if it can't tell
+}
+
+// providers holds the registered providers by name
+var providers = map[string]registeredProvider{}
+
+// SetAPIKey sets the API key a provider uses when no environment variable gives one
+func (c *Client) SetAPIKey(provider, key string) {
+	c.keys[provider] = key
+}
+
+// SetUntrustedURLs makes the client give API keys only to providers sending
+// requests to their own hosts, for API URLs from a source that mustn't
+// receive the keys, such as the config of a cloned repository
+func (c *Client) SetUntrustedURLs(untrusted bool) {
+	c.untrustedURLs = untrusted
+}
+
+// apiKeyFor returns the API key of a provider sending requests to apiURL:
+// OLLAMA_COMMIT_API_KEY, then the provider's own variable, then the key from
+// the configuration. If the client doesn't trust its URLs, there is no key
+// unless apiURL is on one of the provider's hosts; a host starting with a dot
+// matches its subdomains.
+func (c *Client) apiKeyFor(provider, variable, apiURL string, hosts ...string) string {
+	if c.untrustedURLs && !onHost(apiURL, hosts) {
+		return ""
+	}
+	if key := os.Getenv(envPrefix + "API_KEY"); key != "" {
+		return key
+	}
+	if key := os.Getenv(variable); key != "" {
+		return key
+	}
+	return c.keys[provider]
+}
+
+// onHost reports whether apiURL is an HTTPS URL on one of the hosts, where a
+// host starting with a dot matches its subdomains
+func onHost(apiURL string, hosts []string) bool {
+	parsed, err := url.Parse(apiURL)
+	if err != nil || parsed.Scheme != "https" {
+		return false
+	}
+	name := strings.ToLower(parsed.Hostname())
+	for _, host := range hosts {
+		if name == host || (strings.HasPrefix(host, ".") && strings.HasSuffix(name, host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointProvider is implemented by providers that may send requests
+// somewhere other than the configured API URL, such as hosted providers
+// given the default local Ollama URL
+type endpointProvider interface {
+	endpoint() string
+}
+
+// orDefaultURL returns apiURL, or the provider's own URL if apiURL is still
+// the default local Ollama URL
+func orDefaultURL(apiURL, providerURL string) string {
+	if apiURL == "" || apiURL == DefaultAPIURL {
+		return providerURL
+	}
+	return apiURL
+}
+
+// ProviderURL returns the URL provider sends requests to, which it was
+// created for with apiURL
+func ProviderURL(provider Provider, apiURL string) string {
+	if endpoint, ok := provider.(endpointProvider); ok {
+		return endpoint.endpoint()
+	}
+	return apiURL
+}
+
+// RegisterProvider makes a provider available under name, replacing any
+// provider registered with the same name. If detect is given, the provider
+// is used for the API URLs it matches unless another provider is configured.
+// Providers matching a URL by its host take precedence over ones matching its
+// path, so openrouter.ai/api/v1/chat/completions goes to openrouter rather
+// than openai.
+func RegisterProvider(name string, factory ProviderFactory, detect func(apiURL *url.URL) bool) {
+	providers[name] = registeredProvider{factory, detect}
+}
+
+// DetectProvider returns the provider an API URL belongs to, the default
+// provider if none claims it. The detectors are first given the URL without
+// its path and query, so the ones recognizing the host win.
+func DetectProvider(apiURL string) string {
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return DefaultProvider
+	}
+	host := &url.URL{Scheme: parsed.Scheme, User: parsed.User, Host: parsed.Host}
+	for _, candidate := range []*url.URL{host, parsed} {
+		for _, name := range ProviderNames() {
+			if detect := providers[name].detect; detect != nil && detect(candidate) {
+				return name
+			}
+		}
+	}
+	return DefaultProvider
+}
+
+// ProviderNames returns the names of the registered providers, sorted
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unknownProviderError reports a provider that isn't registered
+func unknownProviderError(name string) error {
+	return fmt.Errorf("unknown provider %q; use one of %s", name, strings.Join(ProviderNames(), ", "))
+}
+
+// NewProvider returns the named provider sending requests to apiURL with
+// client, or the provider detected from apiURL if name is empty
+func NewProvider(client *Client, name, apiURL string) (Provider, error) {
+	if name == "" {
+		name = DetectProvider(apiURL)
+	}
+	registered, ok := providers[name]
+	if !ok {
+		return nil, unknownProviderError(name)
+	}
+	return registered.factory(client, apiURL), nil
+}
+
+// sendPrompt sends the prompt with provider and returns the generated text
+func (c *Client) sendPrompt(provider Provider, prompt Prompt) (string, error) {
+	text, _, err := c.generateText(context.Background(), provider, prompt)
+	return text, err
+}
+
+// generateText sends the prompt with provider, retrying transient failures as
+// the client is configured to, and returns the generated text along with the
+// conversation state for a follow-up prompt if the provider keeps one
+func (c *Client) generateText(ctx context.Context, provider Provider, prompt Prompt) (string, []int, error) {
+	send := func() (string, []int, error) {
+		if conversational, ok := provider.(ConversationProvider); ok {
+			return conversational.GenerateConversation(ctx, prompt)
+		}
+		text, err := provider.Generate(ctx, prompt)
+		return text, nil, err
+	}
+
+	text, conversation, err := send()
+	for attempt := 0; err != nil && ctx.Err() == nil && attempt < c.retriesFor(err); attempt++ {
+		delay := retryWait(err, attempt)
+		if c.OnRetry != nil {
+			c.OnRetry(delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", nil, fmt.Errorf("request cancelled: %w", ctx.Err())
+		}
+		text, conversation, err = send()
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Remove quotes if they're wrapping the message
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && ((strings.HasPrefix(text, "\"") && strings.HasSuffix(text, "\"")) ||
+		(strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'"))) {
+		text = text[1 : len(text)-1]
+	}
+	return text, conversation, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import "testing"
+
+func TestDetectProvider(t *testing.T) {
+	tests := map[string]string{
+		"http://localhost:11434/api/generate":                                      "ollama",
+		"https://api.openai.com/v1/chat/completions":                               "openai",
+		"http://gpu-box:8000/v1/chat/completions":                                  "openai",
+		"https://openrouter.ai/api/v1/chat/completions":                            "openrouter",
+		"https://openrouter.ai/api/v1":                                             "openrouter",
+		"https://api.groq.com/openai/v1/chat/completions":                          "groq",
+		"https://api.mistral.ai/v1/chat/completions":                               "mistral",
+		"https://team.openai.azure.com/openai/deployments/gpt-4o/chat/completions": "azure",
+		"https://api.anthropic.com/v1/messages":                                    "anthropic",
+		"http://proxy.internal/v1/messages":                                        "anthropic",
+		"http://localhost:1234/v1/chat/completions":                                "lmstudio",
+		"http://localhost:8080/completion":                                         "llamacpp",
+		"http://tgi.internal/generate":                                             "tgi",
+	}
+	for apiURL, want := range tests {
+		if got := DetectProvider(apiURL); got != want {
+			t.Errorf("DetectProvider(%q) = %q, want %q", apiURL, got, want)
+		}
+	}
+}
+
+func TestAPIKeysForUntrustedURLs(t *testing.T) {
+	t.Setenv("OLLAMA_COMMIT_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	tests := []struct {
+		provider, apiURL string
+		untrusted        bool
+		want             string
+	}{
+		{"openai", "https://evil.example/v1", false, "sk-env"},
+		{"openai", "https://evil.example/v1", true, ""},
+		{"openai", "http://api.openai.com/v1", true, ""},
+		{"openai", "https://api.openai.com/v1", true, "sk-env"},
+		{"anthropic", "https://evil.example", true, ""},
+		{"anthropic", "https://api.anthropic.com", true, "sk-config"},
+		{"azure", "https://team.openai.azure.com", true, "sk-config"},
+		{"azure", "https://openai.azure.com.evil.example", true, ""},
+	}
+	for _, tt := range tests {
+		client := NewClient()
+		client.SetAPIKey(tt.provider, "sk-config")
+		client.SetUntrustedURLs(tt.untrusted)
+		provider, err := NewProvider(client, tt.provider, tt.apiURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var key string
+		switch p := provider.(type) {
+		case openAIProvider:
+			key = p.apiKey
+		case anthropicProvider:
+			key = p.apiKey
+		case azureProvider:
+			key = p.apiKey
+		}
+		if key != tt.want {
+			t.Errorf("key of %s for %s (untrusted %v) = %q, want %q", tt.provider, tt.apiURL, tt.untrusted, key, tt.want)
+		}
+	}
+}
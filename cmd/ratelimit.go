@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retryBaseDelay is the backoff before the first retry, doubled for each further attempt
+const retryBaseDelay = 500 * time.Millisecond
+
+// rateLimitRetries is how many times a rate limited request is retried when no retry count is configured
+const rateLimitRetries = 3
+
+// maxRetryAfter is the longest Retry-After delay waited for; the request fails if the server asks for more
+const maxRetryAfter = 5 * time.Minute
+
+// rateLimiter is a token bucket allowing rps requests per second on average,
+// with bursts of up to max(1, rps) requests
+type rateLimiter struct {
+	mu       sync.Mutex
+	rps      float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// SetRetries sets how many times requests failing with a network error or a
+// server error are retried, with jittered exponential backoff
+func (c *Client) SetRetries(retries int) {
+	c.retries = retries
+}
+
+// SetRetryOnRateLimit enables retrying requests rejected with 429 Too Many
+// Requests after the delay given by the Retry-After header, up to the
+// configured number of retries (or 3 if none are configured)
+func (c *Client) SetRetryOnRateLimit(enabled bool) {
+	c.retryOnRateLimit = enabled
+}
+
+// SetRateLimit limits the requests the client sends to rps per second; zero means unlimited
+func (c *Client) SetRateLimit(rps float64) {
+	if rps <= 0 {
+		c.limiter = nil
+		return
+	}
+
+	capacity := rps
+	if capacity < 1 {
+		capacity = 1
+	}
+	c.limiter = &rateLimiter{rps: rps, capacity: capacity, tokens: capacity, last: time.Now()}
+}
+
+// SetMaxConcurrency limits how many requests the client has in flight at
+// once, such as serve handling several clients; zero means unlimited. It must
+// be called before any request is sent.
+func (c *Client) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		c.inFlight = nil
+		return
+	}
+	c.inFlight = make(chan struct{}, n)
+}
+
+// acquireSlot blocks until another request may be in flight and returns the
+// function releasing the slot again
+func (c *Client) acquireSlot() func() {
+	inFlight := c.inFlight
+	if inFlight == nil {
+		return func() {}
+	}
+	inFlight <- struct{}{}
+	return func() { <-inFlight }
+}
+
+// wait blocks until the bucket has a token and takes it
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rps
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		delay := time.Duration((1 - l.tokens) / l.rps * float64(time.Second))
+		time.Sleep(delay)
+		l.tokens = 1
+		l.last = time.Now()
+	}
+	l.tokens--
+}
+
+// waitForRateLimit blocks until the next request may be sent
+func (c *Client) waitForRateLimit() {
+	if c.limiter != nil {
+		c.limiter.wait()
+	}
+}
+
+// isRetryable reports whether a failed request is worth retrying
+func isRetryable(err error) bool {
+	if errors.Is(err, errStreamStalled) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retriesFor returns how many times a request failing with err may be retried
+func (c *Client) retriesFor(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests {
+		if !c.retryOnRateLimit || statusErr.RetryAfter > maxRetryAfter {
+			return 0
+		}
+		if c.retries > 0 {
+			return c.retries
+		}
+		return rateLimitRetries
+	}
+	if isRetryable(err) {
+		return c.retries
+	}
+	return 0
+}
+
+// retryWait returns how long to wait before the given retry, honoring the
+// server's Retry-After delay if it sent one
+func retryWait(err error, attempt int) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	return retryDelay(attempt)
+}
+
+// parseRetryAfter returns the delay a Retry-After header asks for, given
+// either in seconds or as an HTTP date, or 0 if the header is missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// retryDelay returns the backoff before the given retry (starting at 0),
+// with full jitter so many clients don't retry in lockstep
+func retryDelay(attempt int) time.Duration {
+	backoff := retryBaseDelay << attempt
+	return time.Duration(rand.Int63n(int64(backoff))) + backoff/10
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	const limit, requests = 2, 8
+
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"response":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetMaxConcurrency(limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.postRequest(context.Background(), server.URL, []byte(`{}`), nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > limit {
+		t.Errorf("%d requests were in flight at once, want at most %d", got, limit)
+	}
+	if got := peak.Load(); got < limit {
+		t.Errorf("at most %d requests were in flight at once, want the limit of %d to be used", got, limit)
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// codeownersLocations are the paths GitHub and GitLab look for a CODEOWNERS file
+var codeownersLocations = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// conventionalPrefix matches a conventional commit type without a scope, e.g. "feat: " or "fix!: "
+var conventionalPrefix = regexp.MustCompile(`^([a-z]+)(!?): `)
+
+// codeownersRule is a single pattern line from a CODEOWNERS file
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// GetChangedFiles returns the paths of the files in the diff
+func GetChangedFiles(opts DiffOptions) ([]string, error) {
+	output, err := opts.runDiff("--name-only")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// InferScope derives a conventional commit scope for the changed files, using
+// the owning team from CODEOWNERS and falling back to a shared top-level directory
+func InferScope(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	if rules := loadCodeowners(); len(rules) > 0 {
+		if scope := scopeFromCodeowners(rules, files); scope != "" {
+			return scope
+		}
+	}
+	return scopeFromDirectory(files)
+}
+
+// ApplyScope inserts scope into a conventional commit subject that has none
+func ApplyScope(message, scope string) string {
+	if scope == "" {
+		return message
+	}
+	match := conventionalPrefix.FindStringSubmatch(message)
+	if match == nil {
+		return message
+	}
+	// Built literally since a scope may contain $, which ReplaceAllString expands
+	return match[1] + "(" + scope + ")" + match[2] + ": " + message[len(match[0]):]
+}
+
+// loadCodeowners parses the repository's CODEOWNERS file, if any
+func loadCodeowners() []codeownersRule {
+	output, err := gitCommand("rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil
+	}
+	root := strings.TrimSpace(string(output))
+
+	for _, location := range codeownersLocations {
+		file, err := os.Open(filepath.Join(root, location))
+		if err != nil {
+			continue
+		}
+		rules := parseCodeowners(file)
+		file.Close()
+		return rules
+	}
+	return nil
+}
+
+// parseCodeowners reads the pattern lines of a CODEOWNERS file
+func parseCodeowners(r io.Reader) []codeownersRule {
+	var rules []codeownersRule
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rules = append(rules, codeownersRule{
+			pattern: codeownersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+	return rules
+}
+
+// codeownersPattern converts a gitignore-style CODEOWNERS pattern into a regexp
+func codeownersPattern(pattern string) *regexp.Regexp {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	// Patterns with a slash are relative to the repository root
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	// A pattern matches the path itself or anything below it
+	if dirOnly {
+		expr.WriteString("/.*$")
+	} else {
+		expr.WriteString("(/.*)?$")
+	}
+	return regexp.MustCompile(expr.String())
+}
+
+// scopeFromCodeowners returns the owner shared by most of the files, the
+// last matching rule winning for each file as in CODEOWNERS itself
+func scopeFromCodeowners(rules []codeownersRule, files []string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, file := range files {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].pattern.MatchString(file) {
+				owner := ownerScope(rules[i].owners[0])
+				counts[owner]++
+				if best == "" || counts[owner] > counts[best] {
+					best = owner
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// ownerScope turns a CODEOWNERS owner ("@org/team", "@user" or an email) into a scope
+func ownerScope(owner string) string {
+	owner = strings.TrimPrefix(owner, "@")
+	if i := strings.LastIndex(owner, "/"); i != -1 {
+		owner = owner[i+1:]
+	}
+	if i := strings.Index(owner, "@"); i != -1 {
+		owner = owner[:i]
+	}
+	return owner
+}
+
+// scopeFromDirectory returns the top-level directory shared by all files, if any
+func scopeFromDirectory(files []string) string {
+	scope := ""
+	for _, file := range files {
+		dir, _, found := strings.Cut(file, "/")
+		if !found || (scope != "" && dir != scope) {
+			return ""
+		}
+		scope = dir
+	}
+	return scope
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyScope(t *testing.T) {
+	tests := []struct {
+		message, scope, want string
+	}{
+		{"feat: add login", "auth", "feat(auth): add login"},
+		{"fix!: drop v1 API", "api", "fix(api)!: drop v1 API"},
+		{"feat: add login", "$1-team", "feat($1-team): add login"},
+		{"feat: add login", "${2}", "feat(${2}): add login"},
+		{"feat(ui): add login", "auth", "feat(ui): add login"},
+		{"Add login", "auth", "Add login"},
+		{"feat: add login", "", "feat: add login"},
+	}
+	for _, tt := range tests {
+		if got := ApplyScope(tt.message, tt.scope); got != tt.want {
+			t.Errorf("ApplyScope(%q, %q) = %q, want %q", tt.message, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestScopeFromCodeowners(t *testing.T) {
+	rules := parseCodeowners(strings.NewReader(`# Owners
+*       @org/core
+/docs/  @org/docs
+*.go    @org/backend
+`))
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"docs/readme.md"}, "docs"},
+		{[]string{"cmd/main.go", "docs/x.md", "util.go"}, "backend"},
+		{[]string{"Makefile"}, "core"},
+	}
+	for _, tt := range tests {
+		if got := scopeFromCodeowners(rules, tt.files); got != tt.want {
+			t.Errorf("scopeFromCodeowners(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
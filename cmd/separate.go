@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StagedChange is a change staged for one file, or both paths of a rename
+type StagedChange struct {
+	Paths []string
+}
+
+// String returns the path of the change, or "old -> new" for a rename
+func (c StagedChange) String() string {
+	return strings.Join(c.Paths, " -> ")
+}
+
+// GetStagedChanges lists the staged changes file by file, keeping renames together
+func GetStagedChanges() ([]StagedChange, error) {
+	output, err := gitOutput("", "diff", "--staged", "--name-status", "-z", "--find-renames")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	// With -z each entry is the status followed by one path, or two for renames and copies
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	var changes []StagedChange
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		change := StagedChange{Paths: []string{fields[i+1]}}
+		if (strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C")) && i+2 < len(fields) {
+			change.Paths = append(change.Paths, fields[i+2])
+			i++
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// SnapshotIndex saves the index as a tree so it can be restored with RestoreIndex
+func SnapshotIndex() (string, error) {
+	output, err := gitOutput("", "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to save the index: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RestoreIndex resets the index to a snapshot. Changes committed since then
+// are no longer shown as staged, and everything else is staged again.
+func RestoreIndex(snapshot string) error {
+	if output, err := gitCommand("read-tree", snapshot).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restore the index: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// StageOnly resets the index to HEAD and stages the change as it is in the
+// snapshot, so the next commit contains just that change
+func StageOnly(snapshot string, change StagedChange) error {
+	reset := []string{"read-tree", "HEAD"}
+	if headOrEmptyTree("") == emptyTree {
+		reset = []string{"read-tree", "--empty"}
+	}
+	if output, err := gitCommand(reset...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to reset the index: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	args := []string{"restore", "--source=" + snapshot, "--staged", "--"}
+	for _, path := range change.Paths {
+		args = append(args, ":(literal)"+path)
+	}
+	if output, err := gitCommand(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to stage %s: %w: %s", change, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
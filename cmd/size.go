@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LargeFile is a changed file exceeding the size warning threshold
+type LargeFile struct {
+	Path string
+	Size int64
+}
+
+// FindLargeFiles returns the changed files larger than limit bytes, as
+// found in the working tree. Deleted files are skipped.
+func FindLargeFiles(opts DiffOptions, limit int64) ([]LargeFile, error) {
+	changes, err := GetFileChanges(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths from git diff are relative to the top of the repository
+	output, err := gitOutput("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(string(output))
+
+	var large []LargeFile
+	for _, change := range changes {
+		if change.Status == 'D' {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, change.Path))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.Size() > limit {
+			large = append(large, LargeFile{Path: change.Path, Size: info.Size()})
+		}
+	}
+	return large, nil
+}
+
+// FormatSize returns a human readable file size
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1<<30))
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of a generation
+const (
+	OutcomeAccepted = "accepted" // The message was committed
+	OutcomeRejected = "rejected" // The user declined the message
+	OutcomeShown    = "shown"    // The message was only printed
+)
+
+// GenerationRecord describes one generated message, kept locally for the
+// stats and history commands
+type GenerationRecord struct {
+	ID        string    `json:"id,omitempty"`
+	Time      time.Time `json:"time"`
+	Repo      string    `json:"repo,omitempty"`
+	Model     string    `json:"model"`
+	LatencyMS int64     `json:"latencyMs"`
+	DiffBytes int       `json:"diffBytes"`
+	DiffHash  string    `json:"diffHash,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// generationsPath returns the file the generation records are appended to
+func generationsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "ollama-commit", "generations.jsonl"), nil
+}
+
+// CurrentRepo returns the top-level directory of the repository, or an empty string outside one
+func CurrentRepo() string {
+	output, err := gitCommand("rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// RecordGeneration appends a record to the local generation log, giving it an
+// ID if it has none
+func RecordGeneration(record GenerationRecord) error {
+	if record.ID == "" {
+		sum := sha256.Sum256([]byte(record.Time.Format(time.RFC3339Nano) + record.Repo + record.Message))
+		record.ID = hex.EncodeToString(sum[:])[:8]
+	}
+	path, err := generationsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation record: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadGenerations reads the local generation log, skipping damaged lines
+func LoadGenerations() ([]GenerationRecord, error) {
+	path, err := generationsPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var records []GenerationRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record GenerationRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// ModelStats summarizes the generations of one model
+type ModelStats struct {
+	Model       string
+	Generations int
+	Accepted    int
+	Rejected    int
+	AvgLatency  time.Duration
+	AvgDiffSize int
+}
+
+// AcceptanceRate is the share of the decided messages that were committed,
+// or -1 if none were accepted or rejected
+func (s ModelStats) AcceptanceRate() float64 {
+	if s.Accepted+s.Rejected == 0 {
+		return -1
+	}
+	return float64(s.Accepted) / float64(s.Accepted+s.Rejected)
+}
+
+// SummarizeGenerations groups the records by model, most used first
+func SummarizeGenerations(records []GenerationRecord) []ModelStats {
+	byModel := map[string]*ModelStats{}
+	var latency, diffBytes = map[string]int64{}, map[string]int{}
+	for _, record := range records {
+		stats, ok := byModel[record.Model]
+		if !ok {
+			stats = &ModelStats{Model: record.Model}
+			byModel[record.Model] = stats
+		}
+		stats.Generations++
+		switch record.Outcome {
+		case OutcomeAccepted:
+			stats.Accepted++
+		case OutcomeRejected:
+			stats.Rejected++
+		}
+		latency[record.Model] += record.LatencyMS
+		diffBytes[record.Model] += record.DiffBytes
+	}
+
+	summary := make([]ModelStats, 0, len(byModel))
+	for model, stats := range byModel {
+		stats.AvgLatency = time.Duration(latency[model]/int64(stats.Generations)) * time.Millisecond
+		stats.AvgDiffSize = diffBytes[model] / stats.Generations
+		summary = append(summary, *stats)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Generations != summary[j].Generations {
+			return summary[i].Generations > summary[j].Generations
+		}
+		return summary[i].Model < summary[j].Model
+	})
+	return summary
+}
+
+// History returns the recorded messages newest first, limited to repo unless
+// it is empty and to the messages containing term (ignoring case) unless it is empty
+func History(records []GenerationRecord, repo, term string) []GenerationRecord {
+	term = strings.ToLower(term)
+	var history []GenerationRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Message == "" || record.ID == "" {
+			continue
+		}
+		if repo != "" && record.Repo != repo {
+			continue
+		}
+		if term != "" && !strings.Contains(strings.ToLower(record.Message), term) {
+			continue
+		}
+		history = append(history, record)
+	}
+	return history
+}
+
+// FindGeneration returns the recorded message whose ID starts with id
+func FindGeneration(records []GenerationRecord, id string) (GenerationRecord, error) {
+	var found []GenerationRecord
+	for _, record := range History(records, "", "") {
+		if record.ID == id {
+			return record, nil
+		}
+		if strings.HasPrefix(record.ID, id) {
+			found = append(found, record)
+		}
+	}
+	switch {
+	case id == "" || len(found) == 0:
+		return GenerationRecord{}, fmt.Errorf("no message with ID %q in the history", id)
+	case len(found) > 1:
+		return GenerationRecord{}, fmt.Errorf("ID %q is ambiguous, it matches %d messages", id, len(found))
+	}
+	return found[0], nil
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errStreamStalled is returned when a streamed generation stops producing tokens before any text arrived
+var errStreamStalled = errors.New("model output stalled")
+
+// streamingProvider is implemented by providers whose requests SetStreaming
+// applies to
+type streamingProvider interface {
+	streams()
+}
+
+// CanStream reports whether provider streams its responses when streaming is set
+func CanStream(provider Provider) bool {
+	_, ok := provider.(streamingProvider)
+	return ok
+}
+
+// SetStreaming makes requests stream the response and cancel the generation
+// if the first token doesn't arrive within firstTokenTimeout, or a later one
+// within idleTimeout. An idle timeout of zero turns streaming off, and a first
+// token timeout of zero leaves the first token to the request timeout.
+func (c *Client) SetStreaming(firstTokenTimeout, idleTimeout time.Duration) {
+	c.firstTokenTimeout = firstTokenTimeout
+	c.idleTimeout = idleTimeout
+}
+
+// streamRequest sends a streaming request and collects the chunks. If the
+// output stalls, the request is cancelled and the partial output returned.
+func (c *Client) streamRequest(parent context.Context, apiURL string, ollamaReq OllamaRequest) (OllamaResponse, error) {
+	var result OllamaResponse
+
+	ollamaReq.Stream = true
+	reqBody, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	release := c.acquireSlot()
+	defer release()
+	c.waitForRateLimit()
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	// A cold model can take much longer for the first token than for the next
+	// ones, and Ollama only sends the response headers once it has loaded, so
+	// the first token has its own timeout covering the whole wait for it
+	var firstTokenMissed atomic.Bool
+	firstToken := time.AfterFunc(c.firstTokenTimeout, func() {
+		firstTokenMissed.Store(true)
+		cancel()
+	})
+	if c.firstTokenTimeout <= 0 {
+		firstToken.Stop()
+	}
+	defer firstToken.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return result, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if parent.Err() != nil {
+		return result, fmt.Errorf("request cancelled: %w", parent.Err())
+	}
+	if firstTokenMissed.Load() {
+		return result, errStreamStalled
+	}
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrAPIUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return result, &StatusError{StatusCode: resp.StatusCode, Body: string(bodyBytes), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	// The body can't be inspected without waiting for it, so only reject web pages
+	if contentType := resp.Header.Get("Content-Type"); strings.Contains(contentType, "html") {
+		return result, notJSONError(contentType)
+	}
+
+	// Read the chunks in the background so a stall can be detected
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- append([]byte(nil), scanner.Bytes()...):
+			case <-ctx.Done():
+				readErr <- ctx.Err()
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	// The idle timeout starts with the first chunk
+	var stalled <-chan time.Time
+	var response, content strings.Builder
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if firstTokenMissed.Load() && response.Len()+content.Len() == 0 {
+					return result, errStreamStalled
+				}
+				if err := <-readErr; err != nil {
+					return result, fmt.Errorf("failed to read response body: %w", err)
+				}
+				result.Response = response.String()
+				result.Content = content.String()
+				return result, nil
+			}
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			var chunk OllamaResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				return result, fmt.Errorf("failed to parse streamed response chunk: %w", err)
+			}
+			response.WriteString(chunk.Response)
+			content.WriteString(chunk.Content)
+			if chunk.PromptEvalCount > 0 || chunk.EvalCount > 0 {
+				result.PromptEvalCount = chunk.PromptEvalCount
+				result.EvalCount = chunk.EvalCount
+			}
+			if len(chunk.Context) > 0 {
+				result.Context = chunk.Context
+			}
+
+			firstToken.Stop()
+			stalled = time.After(c.idleTimeout)
+
+		case <-stalled:
+			cancel()
+			result.Response = response.String()
+			result.Content = content.String()
+			if strings.TrimSpace(result.Response+result.Content) == "" {
+				return result, errStreamStalled
+			}
+			if c.OnStall != nil {
+				c.OnStall(c.idleTimeout)
+			}
+			return result, nil
+
+		case <-parent.Done():
+			return result, fmt.Errorf("request cancelled: %w", parent.Err())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowStreamServer waits before each of the given chunks, flushing them one by one
+func slowStreamServer(t *testing.T, delays []time.Duration, chunks []string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i, chunk := range chunks {
+			select {
+			case <-time.After(delays[i]):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write([]byte(chunk + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// streamingClient returns a client with streaming enabled
+func streamingClient(firstToken, idle time.Duration) *Client {
+	client := NewClient()
+	client.SetStreaming(firstToken, idle)
+	return client
+}
+
+func TestStreamFirstTokenTimeout(t *testing.T) {
+	// The model takes longer to load than the idle timeout, then streams quickly
+	server := slowStreamServer(t,
+		[]time.Duration{150 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		[]string{`{"response":"Add "}`, `{"response":"login"}`, `{"response":"","done":true}`})
+	client := streamingClient(time.Second, 50*time.Millisecond)
+
+	resp, err := client.streamRequest(context.Background(), server.URL, OllamaRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("streamRequest() error = %v", err)
+	}
+	if resp.Response != "Add login" {
+		t.Errorf("Response = %q, want %q", resp.Response, "Add login")
+	}
+}
+
+func TestStreamStalls(t *testing.T) {
+	tests := []struct {
+		name       string
+		firstToken time.Duration
+		delays     []time.Duration
+		want       string
+		wantErr    error
+		wantStall  bool
+	}{
+		{
+			name:       "no first token",
+			firstToken: 50 * time.Millisecond,
+			delays:     []time.Duration{time.Second, 0},
+			wantErr:    errStreamStalled,
+		},
+		{
+			name:       "stall after the first token",
+			firstToken: time.Second,
+			delays:     []time.Duration{0, time.Second},
+			want:       "Add ",
+			wantStall:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := slowStreamServer(t, tt.delays, []string{`{"response":"Add "}`, `{"response":"login"}`})
+			client := streamingClient(tt.firstToken, 50*time.Millisecond)
+			var stalled time.Duration
+			client.OnStall = func(idle time.Duration) { stalled = idle }
+
+			resp, err := client.streamRequest(context.Background(), server.URL, OllamaRequest{Model: "m"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("streamRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if resp.Response != tt.want {
+				t.Errorf("Response = %q, want %q", resp.Response, tt.want)
+			}
+			if got := stalled != 0; got != tt.wantStall {
+				t.Errorf("OnStall called = %v, want %v", got, tt.wantStall)
+			}
+		})
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	for name, want := range map[string]bool{"ollama": true, "openai": false, "anthropic": false} {
+		provider, err := NewProvider(NewClient(), name, DefaultAPIURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CanStream(provider); got != want {
+			t.Errorf("CanStream(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
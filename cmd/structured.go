@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// structuredInstructions is appended to the prompt when requesting structured output
+const structuredInstructions = `
+
+Respond with a JSON object with the following fields:
+- "type": the conventional commit type (feat, fix, docs, refactor, test, chore, ...)
+- "scope": an optional short scope, or an empty string
+- "subject": the summary line in imperative mood, without the type prefix
+- "body": an optional longer description, or an empty string`
+
+// StructuredCommit is the commit message object requested in structured mode
+type StructuredCommit struct {
+	Type    string `json:"type"`
+	Scope   string `json:"scope"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// String assembles the structured fields into a commit message
+func (c StructuredCommit) String() string {
+	subject := strings.TrimSpace(c.Subject)
+	if c.Type != "" {
+		prefix := strings.TrimSpace(c.Type)
+		if scope := strings.TrimSpace(c.Scope); scope != "" {
+			prefix += "(" + scope + ")"
+		}
+		subject = prefix + ": " + subject
+	}
+
+	if body := strings.TrimSpace(c.Body); body != "" {
+		return subject + "\n\n" + body
+	}
+	return subject
+}
+
+// GenerateStructuredCommitMessage asks the model for a JSON commit object using
+// Ollama's format parameter and assembles the message from its fields.
+// An empty schema requests plain JSON mode. If the server rejects the format
+// parameter it falls back to the plain text mode.
+func (c *Client) GenerateStructuredCommitMessage(provider Provider, gitDiff, model, promptTemplate string, schema json.RawMessage, options *Options) (string, error) {
+	return c.GenerateStructuredCommitMessageContext(context.Background(), provider, gitDiff, model, promptTemplate, schema, options)
+}
+
+// GenerateStructuredCommitMessageContext is like GenerateStructuredCommitMessage, giving up when ctx is done
+func (c *Client) GenerateStructuredCommitMessageContext(ctx context.Context, provider Provider, gitDiff, model, promptTemplate string, schema json.RawMessage, options *Options) (string, error) {
+	format := schema
+	if len(format) == 0 {
+		format = json.RawMessage(`"json"`)
+	}
+
+	prompt := Prompt{
+		Model:   model,
+		Text:    fmt.Sprintf(promptTemplate, gitDiff) + structuredInstructions,
+		Format:  format,
+		Options: options,
+	}
+
+	output, _, err := c.generateText(ctx, provider, prompt)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+		// Older servers reject the format parameter
+		return c.GenerateCommitMessageContext(ctx, provider, gitDiff, model, promptTemplate, options)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := parseStructuredCommit(output)
+	if err != nil {
+		// The server ignored the format, so the output is already plain text
+		return output, nil
+	}
+	return commit.String(), nil
+}
+
+// parseStructuredCommit decodes the model output into a StructuredCommit,
+// tolerating surrounding text or code fences around the JSON object
+func parseStructuredCommit(output string) (StructuredCommit, error) {
+	var commit StructuredCommit
+
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return commit, fmt.Errorf("no JSON object in output")
+	}
+
+	if err := json.Unmarshal([]byte(output[start:end+1]), &commit); err != nil {
+		return commit, fmt.Errorf("failed to parse structured output: %w", err)
+	}
+	if strings.TrimSpace(commit.Subject) == "" {
+		return commit, fmt.Errorf("structured output has no subject")
+	}
+	return commit, nil
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// submoduleChange is a submodule pointer update found in a diff
+type submoduleChange struct {
+	path      string
+	oldCommit string
+	newCommit string
+}
+
+// parseSubmoduleChanges finds the submodule pointer updates in a diff
+func parseSubmoduleChanges(diff string) []submoduleChange {
+	var changes []submoduleChange
+	var current *submoduleChange
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			// "diff --git a/<path> b/<path>"
+			path := line[strings.LastIndex(line, " b/")+3:]
+			changes = append(changes, submoduleChange{path: path})
+			current = &changes[len(changes)-1]
+		case current == nil:
+		case strings.HasPrefix(line, "-Subproject commit "):
+			current.oldCommit = strings.Fields(line)[2]
+		case strings.HasPrefix(line, "+Subproject commit "):
+			current.newCommit = strings.Fields(line)[2]
+		}
+	}
+
+	// Keep only the entries that are submodule updates
+	var updates []submoduleChange
+	for _, change := range changes {
+		if change.oldCommit != "" && change.newCommit != "" {
+			updates = append(updates, change)
+		}
+	}
+	return updates
+}
+
+// SubmoduleContext lists the commits included in each submodule update of
+// the diff, so the model can describe what a pointer bump brings in. It
+// returns an empty string if there are no updates or the submodules aren't
+// checked out.
+func SubmoduleContext(diff string) string {
+	changes := parseSubmoduleChanges(diff)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// Paths in the diff are relative to the top of the repository, not the working directory
+	root, err := gitOutput("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+
+	var context strings.Builder
+	for _, change := range changes {
+		dir := filepath.Join(strings.TrimSpace(string(root)), filepath.FromSlash(change.path))
+		output, err := gitOutput(dir, "log", "--oneline", "--no-decorate", change.oldCommit+".."+change.newCommit)
+		if err != nil || len(strings.TrimSpace(string(output))) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&context, "Submodule %s %s..%s includes these commits:\n%s\n",
+			change.path, shortHash(change.oldCommit), shortHash(change.newCommit), strings.TrimSpace(string(output)))
+	}
+
+	if context.Len() == 0 {
+		return ""
+	}
+	return "\n" + context.String()
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
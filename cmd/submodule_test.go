@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubmoduleContextFromSubdirectory(t *testing.T) {
+	library := newTestRepo(t)
+	dir := newTestRepo(t)
+	runGit(t, dir, "-c", "protocol.file.allow=always", "submodule", "add", "-q", library, "vendor/lib")
+	runGit(t, dir, "commit", "-q", "-m", "Add lib")
+
+	// Move the submodule forward by one commit
+	submodule := filepath.Join(dir, "vendor", "lib")
+	runGit(t, submodule, "config", "user.name", "Test")
+	runGit(t, submodule, "config", "user.email", "test@example.com")
+	writeFile(t, filepath.Join(submodule, "file.txt"), "one\ntwo\n")
+	runGit(t, submodule, "commit", "-q", "-am", "Teach lib to count to two")
+	runGit(t, dir, "add", "vendor/lib")
+
+	// Run from a directory where the diff's paths don't resolve
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, filepath.Join(dir, "docs"))
+
+	diff, err := GetGitDiff(DiffOptions{})
+	if err != nil {
+		t.Fatalf("GetGitDiff() error = %v", err)
+	}
+	context := SubmoduleContext(diff)
+	if !strings.Contains(context, "Submodule vendor/lib") || !strings.Contains(context, "Teach lib to count to two") {
+		t.Errorf("SubmoduleContext() = %q, want the commit of vendor/lib", context)
+	}
+}
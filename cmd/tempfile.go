@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// tempFilePrefix makes the tool's temp files recognizable in the temp directory
+const tempFilePrefix = "ollama-commit-"
+
+var (
+	tempFilesMu sync.Mutex
+	tempFiles   = make(map[string]struct{})
+)
+
+// CreateTempFile creates a file in the OS temp directory that is removed by
+// CleanupTempFiles, including when the process is interrupted
+func CreateTempFile(pattern string) (*os.File, error) {
+	file, err := os.CreateTemp("", tempFilePrefix+pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	tempFilesMu.Lock()
+	tempFiles[file.Name()] = struct{}{}
+	tempFilesMu.Unlock()
+	return file, nil
+}
+
+// RemoveTempFile removes a file created with CreateTempFile
+func RemoveTempFile(path string) {
+	tempFilesMu.Lock()
+	delete(tempFiles, path)
+	tempFilesMu.Unlock()
+	os.Remove(path)
+}
+
+// CleanupTempFiles removes all temp files that are still around
+func CleanupTempFiles() {
+	tempFilesMu.Lock()
+	defer tempFilesMu.Unlock()
+	for path := range tempFiles {
+		os.Remove(path)
+		delete(tempFiles, path)
+	}
+}
+
+// HandleInterrupts removes the temp files and exits when the process is
+// interrupted or terminated
+func HandleInterrupts() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go cleanupOnSignal(signals, os.Exit)
+}
+
+// cleanupOnSignal waits for a signal, then removes the temp files and exits
+// with the status of an interrupted process
+func cleanupOnSignal(signals <-chan os.Signal, exit func(int)) {
+	<-signals
+	CleanupTempFiles()
+	exit(130)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateTempFile(t *testing.T) {
+	file, err := CreateTempFile("msg-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer RemoveTempFile(file.Name())
+
+	if !strings.HasPrefix(filepath.Base(file.Name()), tempFilePrefix) {
+		t.Errorf("temp file %q lacks the %q prefix", file.Name(), tempFilePrefix)
+	}
+	if filepath.Dir(file.Name()) != filepath.Clean(os.TempDir()) {
+		t.Errorf("temp file %q is outside %q", file.Name(), os.TempDir())
+	}
+}
+
+func TestCleanupOnSignal(t *testing.T) {
+	file, err := CreateTempFile("msg-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	signals := make(chan os.Signal, 1)
+	signals <- os.Interrupt
+	status := -1
+	cleanupOnSignal(signals, func(code int) { status = code })
+
+	if status != 130 {
+		t.Errorf("exit status = %d, want 130", status)
+	}
+	if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+		t.Errorf("temp file %q still exists after the interrupt (stat error %v)", file.Name(), err)
+		os.Remove(file.Name())
+	}
+}
+
+func TestRemoveTempFile(t *testing.T) {
+	file, err := CreateTempFile("msg-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	RemoveTempFile(file.Name())
+
+	if _, err := os.Stat(file.Name()); !os.IsNotExist(err) {
+		t.Errorf("temp file %q still exists (stat error %v)", file.Name(), err)
+	}
+	tempFilesMu.Lock()
+	defer tempFilesMu.Unlock()
+	if _, tracked := tempFiles[file.Name()]; tracked {
+		t.Errorf("temp file %q is still tracked", file.Name())
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxConventionsSize caps how much of a conventions file is added to the prompt
+const maxConventionsSize = 16 * 1024
+
+// PromptPresets holds the built-in prompt templates selectable with -template
+var PromptPresets = map[string]string{
+	"concise": `Generate a single-line git commit message for the following changes.
+Use imperative mood and keep it under 50 characters.
+
+Respond ONLY with the commit message, no other text, explanation, or quotes.
+
+Changes:
+%s`,
+	"detailed": `Generate a git commit message for the following changes.
+Start with a summary line in imperative mood under 50 characters, followed by a blank line
+and a body that explains what changed and why, wrapped at 72 characters.
+
+Respond ONLY with the commit message, no other text, explanation, or quotes.
+
+Changes:
+%s`,
+	"conventional": `Generate a git commit message for the following changes using the Conventional Commits format:
+<type>(<optional scope>): <description>
+
+Valid types are feat, fix, docs, style, refactor, perf, test, build, ci and chore.
+Use imperative mood and keep the first line under 50 characters. Add a body after a blank line if necessary.
+
+Respond ONLY with the commit message, no other text, explanation, or quotes.
+
+Changes:
+%s`,
+	"gitmoji": `Generate a git commit message for the following changes using the gitmoji convention.
+Start the first line with the single most fitting gitmoji (for example ✨ for a feature, 🐛 for a bug fix,
+📝 for documentation, ♻️ for a refactor), followed by a short description in imperative mood.
+
+Respond ONLY with the commit message, no other text, explanation, or quotes.
+
+Changes:
+%s`,
+}
+
+// GetPromptPreset returns the built-in prompt template with the given name
+func GetPromptPreset(name string) (string, error) {
+	template, ok := PromptPresets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q (available: %v)", name, PromptPresetNames())
+	}
+	return template, nil
+}
+
+// PromptPresetNames returns the names of the built-in prompt templates in sorted order
+func PromptPresetNames() []string {
+	names := make([]string, 0, len(PromptPresets))
+	for name := range PromptPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadConventions reads a project conventions file, returning whether it had
+// to be truncated to maxConventionsSize
+func LoadConventions(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read conventions file: %v", err)
+	}
+
+	truncated := len(data) > maxConventionsSize
+	if truncated {
+		data = data[:maxConventionsSize]
+	}
+	return strings.TrimSpace(string(data)), truncated, nil
+}
+
+// WithConventions prepends the project conventions to a prompt template as standing instructions
+func WithConventions(promptTemplate, conventions string) string {
+	if conventions == "" {
+		return promptTemplate
+	}
+	// Escape verbs so the conventions survive fmt.Sprintf
+	conventions = strings.ReplaceAll(conventions, "%", "%%")
+	return "Follow these project conventions for commit messages:\n" + conventions + "\n\n" + promptTemplate
+}
+
+// WithInstruction prepends an extra instruction to a prompt template
+func WithInstruction(promptTemplate, instruction string) string {
+	if instruction == "" {
+		return promptTemplate
+	}
+	return strings.ReplaceAll(instruction, "%", "%%") + "\n\n" + promptTemplate
+}
+
+// DominantExtension returns the file extension (e.g. ".sql") shared by more
+// than half of the files, or an empty string if no extension dominates
+func DominantExtension(files []string) string {
+	counts := make(map[string]int)
+	for _, file := range files {
+		if ext := strings.ToLower(filepath.Ext(file)); ext != "" {
+			counts[ext]++
+		}
+	}
+
+	for ext, count := range counts {
+		if count*2 > len(files) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// FileTypeHint returns the configured hint for the dominant file type of the changes
+func FileTypeHint(hints map[string]string, files []string) string {
+	if len(hints) == 0 {
+		return ""
+	}
+
+	ext := DominantExtension(files)
+	if ext == "" {
+		return ""
+	}
+	if hint, ok := hints[ext]; ok {
+		return hint
+	}
+	// Allow keys without the leading dot
+	return hints[strings.TrimPrefix(ext, ".")]
+}
+
+// IntentHint turns the author's description of the change's purpose into a
+// prompt instruction. The diff stays the main source of what changed.
+func IntentHint(hint string) string {
+	hint = strings.TrimSpace(hint)
+	if hint == "" {
+		return ""
+	}
+	return fmt.Sprintf("The author describes the purpose of these changes as: %q\n"+
+		"Treat this as true and use it to explain why the changes were made, "+
+		"but describe what changed based on the diff.", hint)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// tgiAPIURL is the generate endpoint of a TGI server on the port its docs map it to
+const tgiAPIURL = "http://localhost:8080/generate"
+
+func init() {
+	RegisterProvider("tgi", newTGIProvider, func(apiURL *url.URL) bool {
+		path := strings.TrimSuffix(apiURL.Path, "/")
+		return strings.HasSuffix(path, "/generate") && !strings.HasSuffix(path, "/api/generate")
+	})
+}
+
+// tgiProvider generates text with the /generate endpoint of Hugging Face's
+// Text Generation Inference
+type tgiProvider struct {
+	client *Client
+	apiURL string
+	apiKey string
+}
+
+// newTGIProvider returns a TGI provider, sending requests to a local server
+// unless another API URL is configured. A base URL is completed with
+// /generate. The token, which self-hosted servers usually don't need, is read
+// from OLLAMA_COMMIT_API_KEY, HF_TOKEN or apiKeys.tgi.
+func newTGIProvider(client *Client, apiURL string) Provider {
+	apiURL = strings.TrimSuffix(orDefaultURL(apiURL, tgiAPIURL), "/")
+	if !strings.HasSuffix(apiURL, "/generate") {
+		apiURL += "/generate"
+	}
+	return tgiProvider{client: client, apiURL: apiURL, apiKey: client.apiKeyFor("tgi", "HF_TOKEN", apiURL, ".endpoints.huggingface.cloud")}
+}
+
+// tgiParameters is the parameters block of a /generate request
+type tgiParameters struct {
+	MaxNewTokens   *int     `json:"max_new_tokens,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	TopP           *float64 `json:"top_p,omitempty"`
+	Seed           *int     `json:"seed,omitempty"`
+	Stop           []string `json:"stop,omitempty"`
+	DoSample       bool     `json:"do_sample"`
+	ReturnFullText bool     `json:"return_full_text"`
+	Details        bool     `json:"details"`
+	Grammar        any      `json:"grammar,omitempty"`
+}
+
+// tgiRequest is a /generate request
+type tgiRequest struct {
+	Inputs     string        `json:"inputs"`
+	Parameters tgiParameters `json:"parameters"`
+}
+
+// tgiResponse is a /generate response
+type tgiResponse struct {
+	GeneratedText string `json:"generated_text"`
+	Details       *struct {
+		GeneratedTokens int `json:"generated_tokens"`
+	} `json:"details"`
+}
+
+func (p tgiProvider) endpoint() string {
+	return p.apiURL
+}
+
+func (p tgiProvider) Name() string {
+	return "tgi"
+}
+
+// Generate sends the prompt with num_predict as max_new_tokens. TGI rejects
+// a temperature of 0 and a top_p of 1, so those select greedy decoding and
+// the full distribution by being left out.
+func (p tgiProvider) Generate(ctx context.Context, prompt Prompt) (string, error) {
+	req := tgiRequest{Inputs: prompt.Text, Parameters: tgiParameters{Details: true}}
+	if options := prompt.Options; options != nil {
+		req.Parameters.MaxNewTokens = options.MaxTokens
+		req.Parameters.Seed = options.Seed
+		req.Parameters.Stop = options.Stop
+		if options.Temperature != nil && *options.Temperature > 0 {
+			req.Parameters.Temperature = options.Temperature
+			req.Parameters.DoSample = true
+		}
+		if options.TopP != nil && *options.TopP > 0 && *options.TopP < 1 {
+			req.Parameters.TopP = options.TopP
+			req.Parameters.DoSample = true
+		}
+	}
+
+	// Ollama's "json" format is any JSON object, anything else a schema
+	if len(prompt.Format) > 0 {
+		schema := prompt.Format
+		var mode string
+		if json.Unmarshal(prompt.Format, &mode) == nil && mode == "json" {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		req.Parameters.Grammar = map[string]any{"type": "json", "value": schema}
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	bodyBytes, err := p.client.postRequest(ctx, p.apiURL, reqBody, header)
+	if err != nil {
+		return "", err
+	}
+
+	// Inference endpoints answer with a list holding one result
+	var resp tgiResponse
+	if trimmed := strings.TrimSpace(string(bodyBytes)); strings.HasPrefix(trimmed, "[") {
+		var list []tgiResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+		if len(list) > 0 {
+			resp = list[0]
+		}
+	} else if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Details != nil {
+		p.client.reportUsage(Usage{CompletionTokens: resp.Details.GeneratedTokens})
+	}
+	if strings.TrimSpace(resp.GeneratedText) == "" {
+		return "", fmt.Errorf("%w; check the URL", ErrEmptyResponse)
+	}
+	return resp.GeneratedText, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultTodoMarkers are the markers -warn-todo looks for when none are configured
+var DefaultTodoMarkers = []string{"TODO", "FIXME", "XXX"}
+
+// hunkHeader matches a hunk header and captures the first line number in the new file
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+
+// AddedLine is a line added by a diff
+type AddedLine struct {
+	Path string
+	Line int // Line number in the new file
+	Text string
+}
+
+// addedLines returns the lines a diff adds along with their file and line number
+func addedLines(diff string) []AddedLine {
+	var lines []AddedLine
+	var path string
+	line := 0
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			path = patchPath(text[4:], "b/")
+		case strings.HasPrefix(text, "@@"):
+			if m := hunkHeader.FindStringSubmatch(text); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(text, "+"):
+			lines = append(lines, AddedLine{Path: path, Line: line, Text: text[1:]})
+			line++
+		case strings.HasPrefix(text, " "):
+			line++
+		}
+	}
+	return lines
+}
+
+// FindTodos returns the added lines containing one of the markers as a whole word
+func FindTodos(diff string, markers []string) []AddedLine {
+	if len(markers) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(markers))
+	for i, marker := range markers {
+		quoted[i] = regexp.QuoteMeta(marker)
+	}
+	pattern := regexp.MustCompile(`\b(` + strings.Join(quoted, "|") + `)\b`)
+
+	var todos []AddedLine
+	for _, line := range addedLines(diff) {
+		if pattern.MatchString(line.Text) {
+			line.Text = strings.TrimSpace(line.Text)
+			todos = append(todos, line)
+		}
+	}
+	return todos
+}
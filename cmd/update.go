@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releasesURL is the GitHub API endpoint describing the latest release
+var releasesURL = "https://api.github.com/repos/mrandiw/ollama-commit/releases/latest"
+
+// checksumsAsset is the release asset listing the SHA-256 of every binary, in sha256sum format
+const checksumsAsset = "checksums.txt"
+
+// signatureAsset is the minisign signature of checksumsAsset, made with
+// `minisign -S -l` so it can be checked with Ed25519 alone
+const signatureAsset = checksumsAsset + ".minisig"
+
+// releasePublicKey is the minisign public key releases are signed with. A
+// release whose checksums aren't signed with it is never installed.
+var releasePublicKey = "RWQsuQjqo8pSVGyurUDoZKwQBgan7Q+JdWcQo7bjuu3uDgSfFlQRZqj8"
+
+// Most bytes downloaded for each kind of release asset, so a compromised or
+// broken server can't exhaust memory
+const (
+	maxBinaryBytes    = 256 << 20
+	maxChecksumsBytes = 1 << 20
+)
+
+// updateClient downloads releases. It is separate from the API client so the
+// Ollama timeouts and host allowlist don't apply.
+var updateClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Release is a published release of ollama-commit
+type Release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the named asset
+func (r Release) assetURL(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+	return "", false
+}
+
+// LatestRelease fetches the latest release from GitHub
+func LatestRelease() (Release, error) {
+	var release Release
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return release, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return release, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return release, fmt.Errorf("failed to check for updates: GitHub returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return release, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if release.TagName == "" {
+		return release, fmt.Errorf("the latest release has no tag")
+	}
+	return release, nil
+}
+
+// ReleaseAssetName is the name of the binary for this platform in a release,
+// e.g. ollama-commit_linux_amd64
+func ReleaseAssetName() string {
+	name := fmt.Sprintf("ollama-commit_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// CompareVersions compares two versions like v1.2.3, returning -1, 0 or 1.
+// A pre-release (v1.2.3-rc1) comes before its release.
+func CompareVersions(a, b string) int {
+	partsA, preA := splitVersion(a)
+	partsB, preB := splitVersion(b)
+	for i := 0; i < 3; i++ {
+		if partsA[i] != partsB[i] {
+			if partsA[i] < partsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	case preA < preB:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitVersion returns the major, minor and patch numbers of a version and its pre-release suffix
+func splitVersion(version string) ([3]int, string) {
+	var parts [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, pre, _ := strings.Cut(version, "-")
+	for i, part := range strings.SplitN(version, ".", 3) {
+		parts[i], _ = strconv.Atoi(part)
+	}
+	return parts, pre
+}
+
+// download returns the body of url, or an error if it is longer than limit bytes
+func download(url string, limit int64) ([]byte, error) {
+	resp, err := updateClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("failed to download %s: larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// verifySignature checks a minisign signature of message against a minisign
+// public key. Only Ed25519 signatures of the message itself (legacy mode) are
+// accepted, as the prehashed mode needs BLAKE2b.
+func verifySignature(publicKey string, message, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != 2+8+ed25519.PublicKeySize || string(key[:2]) != "Ed" {
+		return fmt.Errorf("invalid public key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	// untrusted comment, signature, trusted comment, global signature
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature")
+	}
+	if string(sig[:2]) != "Ed" {
+		return fmt.Errorf("unsupported signature algorithm %q", sig[:2])
+	}
+	if !bytes.Equal(sig[2:10], keyID) {
+		return fmt.Errorf("signed with an unknown key")
+	}
+	if !ed25519.Verify(pub, message, sig[10:]) {
+		return fmt.Errorf("signature doesn't match")
+	}
+
+	// The global signature covers the trusted comment, so it can't be swapped
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature")
+	}
+	comment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(pub, append(sig[10:], comment...), global) {
+		return fmt.Errorf("trusted comment signature doesn't match")
+	}
+	return nil
+}
+
+// expectedChecksum finds the SHA-256 of the named file in a sha256sum listing
+func expectedChecksum(listing []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(string(listing)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// DownloadRelease downloads this platform's binary from the release and
+// checks it against the release's checksums, whose signature is checked
+// against the public key built into ollama-commit
+func DownloadRelease(release Release) ([]byte, error) {
+	name := ReleaseAssetName()
+	binaryURL, ok := release.assetURL(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s (%s)", release.TagName, runtime.GOOS, runtime.GOARCH, name)
+	}
+	checksumsURL, ok := release.assetURL(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify the download with", release.TagName, checksumsAsset)
+	}
+	signatureURL, ok := release.assetURL(signatureAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s to verify its checksums with", release.TagName, signatureAsset)
+	}
+
+	listing, err := download(checksumsURL, maxChecksumsBytes)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := download(signatureURL, maxChecksumsBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(releasePublicKey, listing, signature); err != nil {
+		return nil, fmt.Errorf("%s of release %s is not authentic: %w", checksumsAsset, release.TagName, err)
+	}
+	expected, ok := expectedChecksum(listing, name)
+	if !ok {
+		return nil, fmt.Errorf("%s of release %s doesn't list %s", checksumsAsset, release.TagName, name)
+	}
+
+	binary, err := download(binaryURL, maxBinaryBytes)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+	return binary, nil
+}
+
+// ReplaceExecutable replaces the running executable with binary and returns its path
+func ReplaceExecutable(binary []byte) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		self = resolved
+	}
+
+	// Write next to the executable so the rename doesn't cross filesystems
+	if err := WriteFileAtomic(self+".new", binary, 0755); err != nil {
+		return "", fmt.Errorf("failed to write the new executable (is %s writable?): %w", filepath.Dir(self), err)
+	}
+
+	// Windows can't replace a running executable, but it can rename it
+	if runtime.GOOS == "windows" {
+		os.Remove(self + ".old")
+		if err := os.Rename(self, self+".old"); err != nil {
+			os.Remove(self + ".new")
+			return "", fmt.Errorf("failed to move the old executable aside: %w", err)
+		}
+	}
+	if err := os.Rename(self+".new", self); err != nil {
+		os.Remove(self + ".new")
+		return "", fmt.Errorf("failed to replace the executable: %w", err)
+	}
+	return self, nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// signingKey returns a minisign public key and a function signing messages
+// with it in legacy mode
+func signingKey(t *testing.T) (string, func(message []byte) []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte("12345678")
+	publicKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+
+	sign := func(message []byte) []byte {
+		sig := ed25519.Sign(priv, message)
+		comment := "timestamp:1700000000\tfile:checksums.txt"
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+		return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), sig...)),
+			comment,
+			base64.StdEncoding.EncodeToString(global)))
+	}
+	return publicKey, sign
+}
+
+func TestVerifySignature(t *testing.T) {
+	publicKey, sign := signingKey(t)
+	otherKey, _ := signingKey(t)
+	listing := []byte("abc123  ollama-commit_linux_amd64\n")
+	signature := sign(listing)
+
+	if err := verifySignature(publicKey, listing, signature); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := verifySignature(publicKey, []byte("def456  ollama-commit_linux_amd64\n"), signature); err == nil {
+		t.Error("signature of another listing accepted")
+	}
+	if err := verifySignature(otherKey, listing, signature); err == nil {
+		t.Error("signature made with another key accepted")
+	}
+	tampered := strings.Replace(string(signature), "file:checksums.txt", "file:other.txt", 1)
+	if err := verifySignature(publicKey, listing, []byte(tampered)); err == nil {
+		t.Error("signature with a changed trusted comment accepted")
+	}
+	if err := verifySignature(publicKey, listing, []byte("not a signature")); err == nil {
+		t.Error("malformed signature accepted")
+	}
+}
+
+func TestDownloadReleaseChecksSignature(t *testing.T) {
+	publicKey, sign := signingKey(t)
+	defer func(key string) { releasePublicKey = key }(releasePublicKey)
+	releasePublicKey = publicKey
+
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	listing := []byte(hex.EncodeToString(sum[:]) + "  " + ReleaseAssetName() + "\n")
+	signature := sign(listing)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/binary":
+			w.Write(binary)
+		case "/checksums":
+			w.Write(listing)
+		case "/signature":
+			w.Write(signature)
+		}
+	}))
+	defer server.Close()
+
+	var release Release
+	release.TagName = "v1.0.0"
+	for name, path := range map[string]string{ReleaseAssetName(): "/binary", checksumsAsset: "/checksums", signatureAsset: "/signature"} {
+		release.Assets = append(release.Assets, struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		}{name, server.URL + path})
+	}
+
+	got, err := DownloadRelease(release)
+	if err != nil || string(got) != string(binary) {
+		t.Fatalf("DownloadRelease() = %q, %v; want the binary", got, err)
+	}
+
+	// Checksums that match the binary but aren't signed with the built-in key
+	releasePublicKey, _ = signingKey(t)
+	if _, err := DownloadRelease(release); err == nil || !strings.Contains(err.Error(), "not authentic") {
+		t.Errorf("DownloadRelease() with another key error = %v, want the checksums to be rejected", err)
+	}
+}
+
+func TestDownloadLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	if _, err := download(server.URL, 100); err != nil {
+		t.Errorf("download at the limit failed: %v", err)
+	}
+	if _, err := download(server.URL, 99); err == nil {
+		t.Error("download over the limit succeeded")
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SentSummary describes what was sent to the model after filtering
+type SentSummary struct {
+	Model            string
+	Bytes            int  // Size of the changes included in the prompt
+	Files            int  // Number of changed files
+	StatOnly         bool // Only a diff --stat summary was sent because the diff was too large
+	IgnoreWhitespace bool // Whitespace-only changes were filtered out
+	SubmoduleContext bool // Submodule commit lists were added
+}
+
+// String formats the summary for display
+func (s SentSummary) String() string {
+	content := "full diff"
+	if s.StatOnly {
+		content = "diff summary only (full diff too large)"
+	}
+
+	var filters []string
+	if s.IgnoreWhitespace {
+		filters = append(filters, "whitespace changes ignored")
+	}
+	if s.SubmoduleContext {
+		filters = append(filters, "submodule commits added")
+	}
+	if len(filters) == 0 {
+		filters = append(filters, "none")
+	}
+
+	return fmt.Sprintf("Sent to %s: %d bytes, %d file(s), %s; filters: %s",
+		s.Model, s.Bytes, s.Files, content, strings.Join(filters, ", "))
+}
+
+// CountDiffFiles returns the number of files in a diff
+func CountDiffFiles(diff string) int {
+	count := 0
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			count++
+		}
+	}
+	return count
+}
+
+// Usage is the token usage reported by the API for a request
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// EstimateTokens gives a rough token count for text, assuming about four
+// characters per token as is typical for English text and code
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// EstimateCost returns the cost of a number of tokens at the given price per token
+func EstimateCost(tokens int, pricePerToken float64) float64 {
+	return float64(tokens) * pricePerToken
+}
+
+// usage returns the token usage reported in the response, from Ollama's
+// counters or an OpenAI-style usage object
+func (r OllamaResponse) usage() Usage {
+	if r.Usage != nil {
+		return Usage{PromptTokens: r.Usage.PromptTokens, CompletionTokens: r.Usage.CompletionTokens}
+	}
+	return Usage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+}
@@ -2,23 +2,177 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 // Config holds the application configuration
 type Config struct {
-	OllamaAPIURL   string `json:"ollamaApiUrl"`
-	DefaultModel   string `json:"defaultModel"`
-	PromptTemplate string `json:"promptTemplate"`
+	OllamaAPIURL            string             `json:"ollamaApiUrl"`
+	DefaultModel            string             `json:"defaultModel"`
+	PromptTemplate          string             `json:"promptTemplate"`
+	FindRenames             int                `json:"findRenames,omitempty"`
+	FindCopies              bool               `json:"findCopies,omitempty"`
+	JSONSchema              json.RawMessage    `json:"jsonSchema,omitempty"`          // Schema used for structured output, plain JSON mode if empty
+	ScopeFromCodeowners     bool               `json:"scopeFromCodeowners,omitempty"` // Add a scope derived from CODEOWNERS to conventional commit subjects
+	ConnectTimeout          int                `json:"connectTimeout,omitempty"`      // Seconds to wait for a connection to the API
+	Timeout                 int                `json:"timeout,omitempty"`             // Seconds to wait for the whole request, 0 means no limit
+	ReviewPromptTemplate    string             `json:"reviewPromptTemplate"`
+	Plumbing                bool               `json:"plumbing,omitempty"`              // Gather the diff with git plumbing commands
+	AllowedModels           []string           `json:"allowedModels,omitempty"`         // Models that may be used, any model if empty
+	ConventionsFile         string             `json:"conventionsFile,omitempty"`       // File with project commit conventions prepended to the prompt
+	ContextFallbackModels   []string           `json:"contextFallbackModels,omitempty"` // Larger-context models tried when the diff doesn't fit
+	AuthorMap               map[string]string  `json:"authorMap,omitempty"`             // Co-author aliases mapped to "Name <email>"
+	StrictAuthorMap         bool               `json:"strictAuthorMap,omitempty"`       // Reject co-author aliases missing from the author map
+	BreakingChangeFooter    bool               `json:"breakingChangeFooter,omitempty"`  // Ask for and normalize a BREAKING CHANGE footer
+	RefsFooter              bool               `json:"refsFooter,omitempty"`            // Add a Refs footer with the ticket from the branch name
+	TicketPattern           string             `json:"ticketPattern,omitempty"`         // Regular expression matching the ticket in the branch name
+	SubjectSeparator        string             `json:"subjectSeparator,omitempty"`      // How the subject is split from the body: blank-line or newline
+	FileTypeHints           map[string]string  `json:"fileTypeHints,omitempty"`         // Prompt hints keyed by file extension, used when one type dominates the changes
+	LineEnding              string             `json:"lineEnding,omitempty"`            // Line endings of the committed message: lf, crlf or auto
+	ResponseFields          []string           `json:"responseFields,omitempty"`        // Field names searched for the generated text in API responses
+	IgnoreWhitespace        bool               `json:"ignoreWhitespace,omitempty"`      // Leave whitespace-only changes out of the diff
+	PRPromptTemplate        string             `json:"prPromptTemplate"`
+	MergePromptTemplate     string             `json:"mergePromptTemplate"`
+	ExplainPromptTemplate   string             `json:"explainPromptTemplate"`
+	MaxBodyBullets          int                `json:"maxBodyBullets,omitempty"`          // Maximum number of bullet points in the body, 0 means unlimited
+	PricePerToken           float64            `json:"pricePerToken,omitempty"`           // Price of one token for cost estimates, 0 disables them
+	Retries                 int                `json:"retries,omitempty"`                 // Times a request failing with a network or server error is retried
+	RateLimitRPS            float64            `json:"rateLimitRps,omitempty"`            // Maximum requests per second sent by this process, 0 means unlimited
+	StreamIdleTimeout       int                `json:"streamIdleTimeout,omitempty"`       // Seconds without a new token before a streamed generation is cancelled
+	Stream                  bool               `json:"stream,omitempty"`                  // Default for -stream
+	StreamFirstTokenTimeout int                `json:"streamFirstTokenTimeout,omitempty"` // Seconds a streamed generation may take to produce its first token, e.g. while the model loads
+	TranscodeMessage        bool               `json:"transcodeMessage,omitempty"`        // Convert the message to the repository's i18n.commitEncoding before committing
+	IncludeNameStatus       bool               `json:"includeNameStatus,omitempty"`       // Send git's name-status metadata along with the diff
+	ModelDefaults           map[string]Options `json:"modelDefaults,omitempty"`           // Model options keyed by model name, overridden by flags
+	MaxMessageBytes         int                `json:"maxMessageBytes,omitempty"`         // Maximum size of the whole message in bytes, 0 means unlimited
+	SizeWarnBytes           int64              `json:"sizeWarnBytes,omitempty"`           // Warn about changed files larger than this, 0 disables the check
+	RefuseLargeFiles        bool               `json:"refuseLargeFiles,omitempty"`        // Stop instead of warning about large files unless -force is given
+	Language                string             `json:"language,omitempty"`                // Language of the message, or "auto" to match the comments in the diff
+	GeneratorCommand        string             `json:"generatorCommand,omitempty"`        // External command generating the message from the diff on stdin, instead of Ollama
+	VerificationCommand     string             `json:"verificationCommand,omitempty"`     // Command such as a test suite whose result is given to the model and added as a Tested trailer
+	VerificationTimeout     int                `json:"verificationTimeout,omitempty"`     // Seconds before the verification command is killed
+	SubjectCase             string             `json:"subjectCase,omitempty"`             // Case of the subject's first letter: preserve, sentence or lower
+	TwoStageThreshold       int                `json:"twoStageThreshold,omitempty"`       // Diff size in bytes from which -two-stage summarizes the changes first
+	AllowedHosts            []string           `json:"allowedHosts,omitempty"`            // Hosts the changes may be sent to, any host if empty
+	AcknowledgedRemote      bool               `json:"acknowledgedRemote,omitempty"`      // Send changes to a non-local API URL without asking
+	EscalationModels        []string           `json:"escalationModels,omitempty"`        // Larger models tried in order when the message is too short
+	MinMessageLength        int                `json:"minMessageLength,omitempty"`        // Messages shorter than this many characters are escalated
+	MaxEscalations          int                `json:"maxEscalations,omitempty"`          // Maximum number of escalation models tried
+	AddNote                 bool               `json:"addNote,omitempty"`                 // Default for -add-note
+	TodoMarkers             []string           `json:"todoMarkers,omitempty"`             // Markers -warn-todo looks for in added lines
+	WarnTodo                bool               `json:"warnTodo,omitempty"`                // Default for -warn-todo
+	MaxConcurrency          int                `json:"maxConcurrency,omitempty"`          // Maximum requests in flight at once, 0 means unlimited
+	GeneratedFiles          []string           `json:"generatedFiles,omitempty"`          // Lock and generated files left out of the diff
+	IncludeGenerated        bool               `json:"includeGenerated,omitempty"`        // Default for -include-generated
+	RetryOnRateLimit        bool               `json:"retryOnRateLimit,omitempty"`        // Retry 429 responses after the Retry-After delay
+	DisableStats            bool               `json:"disableStats,omitempty"`            // Don't record generations for stats and history
+	Provider                string             `json:"provider,omitempty"`                // Backend the requests are sent to, ollama by default
+	APIKeys                 map[string]string  `json:"apiKeys,omitempty"`                 // API keys by provider, used when no environment variable gives one
+	AzureDeployment         string             `json:"azureDeployment,omitempty"`         // Deployment of the azure provider, the model name if empty
+	AzureAPIVersion         string             `json:"azureApiVersion,omitempty"`         // api-version of the azure provider
+	BedrockRegion           string             `json:"bedrockRegion,omitempty"`           // AWS region of the bedrock provider, AWS_REGION if empty
+
+	source     string   // Config file the settings were loaded from, empty for defaults
+	loadErr    error    // Error parsing the config file, reported by Validate
+	ignored    []string // Settings of a repository config file that only the home config may set
+	deprecated []string // Warnings about deprecated settings of the config files
+	repoURL    string   // API URL set by the repository, through its config file or .env, which API keys aren't sent to
 }
 
-// LoadConfig loads configuration from file or returns defaults
+// localConfigFile is the config file of a repository, read from the working directory
+const localConfigFile = "ollama-commit.json"
+
+// LoadConfig loads the configuration from ./ollama-commit.json, or else
+// ~/.ollama-commit.json, merged with the defaults. Anyone who can commit to a
+// repository controls its config file, so settings that run commands are
+// only read from the home config.
 func LoadConfig() Config {
-	// Default configuration
-	defaultConfig := Config{
-		OllamaAPIURL: "http://localhost:11434/api/generate",
-		DefaultModel: "gemma3:1b",
+	home := DefaultConfig()
+	if configFile, err := homeConfigPath(); err == nil {
+		if data, err := os.ReadFile(configFile); err == nil {
+			home = parseConfig(configFile, data)
+		}
+	}
+
+	data, err := os.ReadFile(localConfigFile)
+	if err != nil {
+		return home
+	}
+	return restrictLocalConfig(parseConfig(localConfigFile, data), home)
+}
+
+// homeOnlySettings are the settings only ~/.ollama-commit.json can set
+var homeOnlySettings = []string{"generatorCommand", "verificationCommand", "acknowledgedRemote", "apiKeys", "bedrockRegion"}
+
+// configFileMode is the mode config files are written with, since they can
+// hold API keys
+const configFileMode = 0600
+
+// restrictLocalConfig gives the settings a repository config may not change
+// the values of the home config, recording the ones the repository set. A
+// repository can narrow the allowlists of the home config but not widen them.
+func restrictLocalConfig(local, home Config) Config {
+	var err error
+	local.AllowedModels, err = intersectAllowlist("allowedModels", local.AllowedModels, home.AllowedModels, func(a, b string) bool { return a == b })
+	local.loadErr = errors.Join(local.loadErr, err)
+	local.AllowedHosts, err = intersectAllowlist("allowedHosts", local.AllowedHosts, home.AllowedHosts, strings.EqualFold)
+	local.loadErr = errors.Join(local.loadErr, err)
+
+	if local.GeneratorCommand != "" && local.GeneratorCommand != home.GeneratorCommand {
+		local.ignored = append(local.ignored, "generatorCommand")
+	}
+	local.GeneratorCommand = home.GeneratorCommand
+	if local.VerificationCommand != "" && local.VerificationCommand != home.VerificationCommand {
+		local.ignored = append(local.ignored, "verificationCommand")
+	}
+	local.VerificationCommand = home.VerificationCommand
+	if local.AcknowledgedRemote && !home.AcknowledgedRemote {
+		local.ignored = append(local.ignored, "acknowledgedRemote")
+	}
+	local.AcknowledgedRemote = home.AcknowledgedRemote
+	if len(local.APIKeys) > 0 && !maps.Equal(local.APIKeys, home.APIKeys) {
+		local.ignored = append(local.ignored, "apiKeys")
+	}
+	local.APIKeys = home.APIKeys
+	local.deprecated = append(home.deprecated, local.deprecated...)
+	if local.BedrockRegion != "" && local.BedrockRegion != home.BedrockRegion {
+		local.ignored = append(local.ignored, "bedrockRegion")
+	}
+	local.BedrockRegion = home.BedrockRegion
+
+	// A repository may point at another server, but it doesn't get the API keys
+	if local.OllamaAPIURL != home.OllamaAPIURL {
+		local.repoURL = local.OllamaAPIURL
+	}
+	return local
+}
+
+// DefaultConfig returns the configuration used when no config file sets a value
+func DefaultConfig() Config {
+	return Config{
+		OllamaAPIURL:            DefaultAPIURL,
+		DefaultModel:            "gemma3:1b",
+		ConnectTimeout:          5,
+		StreamIdleTimeout:       30,
+		StreamFirstTokenTimeout: 300,
+		VerificationTimeout:     120,
+		SubjectSeparator:        SeparatorBlankLine,
+		LineEnding:              LineEndingLF,
+		SubjectCase:             SubjectCasePreserve,
+		TwoStageThreshold:       20000,
+		MaxConcurrency:          4,
+		MinMessageLength:        10,
+		MaxEscalations:          2,
+		TodoMarkers:             DefaultTodoMarkers,
+		GeneratedFiles:          DefaultGeneratedFiles,
 		PromptTemplate: `Generate a concise and descriptive git commit message based on the following changes.
 Follow best practices for git commit messages: use imperative mood, keep it under 50 characters for the first line,
 and add more details in a body if necessary. 
@@ -26,39 +180,531 @@ and add more details in a body if necessary.
 Respond ONLY with the commit message, no other text, explanation, or quotes. 
 Just the commit message that would be used with 'git commit -m'.
 
+Changes:
+%s`,
+		PRPromptTemplate: `Write a pull request title and description for the following branch.
+The title should be concise and in imperative mood. The description should summarize
+the purpose of the changes and list them as a markdown checklist.
+
+%s`,
+		MergePromptTemplate: `Generate a git commit message for the following merge.
+Summarize what the merged branch brings in, based on its commits, rather than
+describing every change. Use imperative mood and keep the first line under 50 characters.
+
+Respond ONLY with the commit message, no other text, explanation, or quotes.
+
+%s`,
+		ReviewPromptTemplate: `Act as an experienced code reviewer. Review the following changes before they are committed.
+Point out potential bugs, missing tests, and style or readability issues.
+Be specific and reference the affected files. If nothing needs attention, say so briefly.
+
+Changes:
+%s`,
+		ExplainPromptTemplate: `Explain the following changes in plain English to a developer who hasn't seen them.
+Start with one or two sentences on what the changes do overall, then walk through them
+file by file, describing what changed and why it matters. Mention anything that changes
+behavior for users. Don't suggest improvements.
+
 Changes:
 %s`,
 	}
+}
 
-	// Look for config file in current directory
-	configFile := "ollama-commit.json"
-	data, err := os.ReadFile(configFile)
+// parseConfig parses a config file and merges it with the defaults. A parse
+// error is kept on the config and reported by Validate.
+func parseConfig(configFile string, data []byte) Config {
+	defaultConfig := DefaultConfig()
+	defaultConfig.source = configFile
 
-	// If no config in current directory, check home directory
-	if err != nil {
-		homeDir, homeDirErr := os.UserHomeDir()
-		if homeDirErr == nil {
-			homeConfig := filepath.Join(homeDir, ".ollama-commit.json")
-			data, err = os.ReadFile(homeConfig)
-		}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		defaultConfig.loadErr = fmt.Errorf("failed to parse %s: %w", configFile, err)
+		return defaultConfig
 	}
 
-	// If config found, unmarshal it
-	if err == nil {
-		var config Config
-		if err := json.Unmarshal(data, &config); err == nil {
-			// Merge with defaults (only set values that are not empty)
-			if config.OllamaAPIURL != "" {
-				defaultConfig.OllamaAPIURL = config.OllamaAPIURL
-			}
-			if config.DefaultModel != "" {
-				defaultConfig.DefaultModel = config.DefaultModel
-			}
-			if config.PromptTemplate != "" {
-				defaultConfig.PromptTemplate = config.PromptTemplate
+	// Merge with defaults (only set values that are not empty)
+	if config.OllamaAPIURL != "" {
+		defaultConfig.OllamaAPIURL = config.OllamaAPIURL
+	}
+	if config.DefaultModel != "" {
+		defaultConfig.DefaultModel = config.DefaultModel
+	}
+	if config.PromptTemplate != "" {
+		defaultConfig.PromptTemplate = config.PromptTemplate
+	}
+	if config.FindRenames > 0 {
+		defaultConfig.FindRenames = config.FindRenames
+	}
+	if config.FindCopies {
+		defaultConfig.FindCopies = config.FindCopies
+	}
+	if len(config.JSONSchema) > 0 {
+		defaultConfig.JSONSchema = config.JSONSchema
+	}
+	if config.ScopeFromCodeowners {
+		defaultConfig.ScopeFromCodeowners = config.ScopeFromCodeowners
+	}
+	if config.ConnectTimeout > 0 {
+		defaultConfig.ConnectTimeout = config.ConnectTimeout
+	}
+	if config.Timeout > 0 {
+		defaultConfig.Timeout = config.Timeout
+	}
+	if config.ReviewPromptTemplate != "" {
+		defaultConfig.ReviewPromptTemplate = config.ReviewPromptTemplate
+	}
+	if config.MergePromptTemplate != "" {
+		defaultConfig.MergePromptTemplate = config.MergePromptTemplate
+	}
+	if config.ExplainPromptTemplate != "" {
+		defaultConfig.ExplainPromptTemplate = config.ExplainPromptTemplate
+	}
+	if config.Plumbing {
+		defaultConfig.Plumbing = config.Plumbing
+	}
+	if len(config.AllowedModels) > 0 {
+		defaultConfig.AllowedModels = config.AllowedModels
+	}
+	if config.ConventionsFile != "" {
+		defaultConfig.ConventionsFile = config.ConventionsFile
+	}
+	if len(config.ContextFallbackModels) > 0 {
+		defaultConfig.ContextFallbackModels = config.ContextFallbackModels
+	}
+	if len(config.AuthorMap) > 0 {
+		defaultConfig.AuthorMap = config.AuthorMap
+	}
+	if config.StrictAuthorMap {
+		defaultConfig.StrictAuthorMap = config.StrictAuthorMap
+	}
+	if config.BreakingChangeFooter {
+		defaultConfig.BreakingChangeFooter = config.BreakingChangeFooter
+	}
+	if config.RefsFooter {
+		defaultConfig.RefsFooter = config.RefsFooter
+	}
+	if config.TicketPattern != "" {
+		defaultConfig.TicketPattern = config.TicketPattern
+	}
+	if config.SubjectSeparator != "" {
+		defaultConfig.SubjectSeparator = config.SubjectSeparator
+	}
+	if len(config.FileTypeHints) > 0 {
+		defaultConfig.FileTypeHints = config.FileTypeHints
+	}
+	if config.LineEnding != "" {
+		defaultConfig.LineEnding = config.LineEnding
+	}
+	if len(config.ResponseFields) > 0 {
+		defaultConfig.ResponseFields = config.ResponseFields
+	}
+	if config.IgnoreWhitespace {
+		defaultConfig.IgnoreWhitespace = config.IgnoreWhitespace
+	}
+	if config.PRPromptTemplate != "" {
+		defaultConfig.PRPromptTemplate = config.PRPromptTemplate
+	}
+	if config.MaxBodyBullets > 0 {
+		defaultConfig.MaxBodyBullets = config.MaxBodyBullets
+	}
+	if config.PricePerToken > 0 {
+		defaultConfig.PricePerToken = config.PricePerToken
+	}
+	if config.Retries > 0 {
+		defaultConfig.Retries = config.Retries
+	}
+	if config.RateLimitRPS > 0 {
+		defaultConfig.RateLimitRPS = config.RateLimitRPS
+	}
+	if config.StreamIdleTimeout > 0 {
+		defaultConfig.StreamIdleTimeout = config.StreamIdleTimeout
+	}
+	if config.Stream {
+		defaultConfig.Stream = config.Stream
+	}
+	if config.StreamFirstTokenTimeout > 0 {
+		defaultConfig.StreamFirstTokenTimeout = config.StreamFirstTokenTimeout
+	}
+	if config.TranscodeMessage {
+		defaultConfig.TranscodeMessage = config.TranscodeMessage
+	}
+	if config.IncludeNameStatus {
+		defaultConfig.IncludeNameStatus = config.IncludeNameStatus
+	}
+	if len(config.ModelDefaults) > 0 {
+		defaultConfig.ModelDefaults = config.ModelDefaults
+	}
+	if config.MaxMessageBytes != 0 {
+		defaultConfig.MaxMessageBytes = config.MaxMessageBytes
+	}
+	if config.SizeWarnBytes != 0 {
+		defaultConfig.SizeWarnBytes = config.SizeWarnBytes
+	}
+	if config.RefuseLargeFiles {
+		defaultConfig.RefuseLargeFiles = config.RefuseLargeFiles
+	}
+	if config.Language != "" {
+		defaultConfig.Language = config.Language
+	}
+	if config.GeneratorCommand != "" {
+		defaultConfig.GeneratorCommand = config.GeneratorCommand
+	}
+	if config.VerificationCommand != "" {
+		defaultConfig.VerificationCommand = config.VerificationCommand
+	}
+	if config.VerificationTimeout != 0 {
+		defaultConfig.VerificationTimeout = config.VerificationTimeout
+	}
+	if config.SubjectCase != "" {
+		defaultConfig.SubjectCase = config.SubjectCase
+	}
+	if config.TwoStageThreshold != 0 {
+		defaultConfig.TwoStageThreshold = config.TwoStageThreshold
+	}
+	if len(config.AllowedHosts) > 0 {
+		defaultConfig.AllowedHosts = config.AllowedHosts
+	}
+	if config.AcknowledgedRemote {
+		defaultConfig.AcknowledgedRemote = config.AcknowledgedRemote
+	}
+	if len(config.EscalationModels) > 0 {
+		defaultConfig.EscalationModels = config.EscalationModels
+	}
+	if config.MinMessageLength != 0 {
+		defaultConfig.MinMessageLength = config.MinMessageLength
+	}
+	if config.MaxEscalations != 0 {
+		defaultConfig.MaxEscalations = config.MaxEscalations
+	}
+	if config.AddNote {
+		defaultConfig.AddNote = config.AddNote
+	}
+	if len(config.TodoMarkers) > 0 {
+		defaultConfig.TodoMarkers = config.TodoMarkers
+	}
+	if config.WarnTodo {
+		defaultConfig.WarnTodo = config.WarnTodo
+	}
+	if config.MaxConcurrency > 0 {
+		defaultConfig.MaxConcurrency = config.MaxConcurrency
+	}
+	if len(config.GeneratedFiles) > 0 {
+		defaultConfig.GeneratedFiles = config.GeneratedFiles
+	}
+	if config.IncludeGenerated {
+		defaultConfig.IncludeGenerated = config.IncludeGenerated
+	}
+	if config.RetryOnRateLimit {
+		defaultConfig.RetryOnRateLimit = config.RetryOnRateLimit
+	}
+	if config.DisableStats {
+		defaultConfig.DisableStats = config.DisableStats
+	}
+	if config.Provider != "" {
+		defaultConfig.Provider = config.Provider
+	}
+	if len(config.APIKeys) > 0 {
+		defaultConfig.APIKeys = config.APIKeys
+	}
+	defaultConfig.applyDeprecatedKeys(data)
+	if config.AzureDeployment != "" {
+		defaultConfig.AzureDeployment = config.AzureDeployment
+	}
+	if config.AzureAPIVersion != "" {
+		defaultConfig.AzureAPIVersion = config.AzureAPIVersion
+	}
+	if config.BedrockRegion != "" {
+		defaultConfig.BedrockRegion = config.BedrockRegion
+	}
+
+	return defaultConfig
+}
+
+// Source returns the path of the config file that was loaded, or an empty string if none was found
+func (c Config) Source() string {
+	return c.source
+}
+
+// APIURLFromRepo reports whether the API URL was set by the repository,
+// through its config file or .env, rather than by the home config, the
+// environment or a flag. API keys are then only sent to the providers' own
+// hosts, so a cloned repository can't collect them.
+func (c Config) APIURLFromRepo() bool {
+	return c.repoURL != "" && c.OllamaAPIURL == c.repoURL
+}
+
+// intersectAllowlist returns the entries allowed by both the repository and
+// the home config, where an empty list allows everything. It returns an error
+// if the two lists have nothing in common.
+func intersectAllowlist(setting string, local, home []string, equal func(a, b string) bool) ([]string, error) {
+	if len(local) == 0 {
+		return home, nil
+	}
+	if len(home) == 0 {
+		return local, nil
+	}
+	var both []string
+	for _, entry := range local {
+		for _, allowed := range home {
+			if equal(entry, allowed) {
+				both = append(both, entry)
+				break
 			}
 		}
 	}
+	if len(both) == 0 {
+		return nil, fmt.Errorf("%s of %s allows nothing that ~/.ollama-commit.json allows (%s)", setting, localConfigFile, strings.Join(home, ", "))
+	}
+	return both, nil
+}
 
-	return defaultConfig
+// deprecatedKeySettings are the settings that held the API key of one
+// provider before apiKeys, by the provider
+var deprecatedKeySettings = map[string]string{
+	"anthropicApiKey":  "anthropic",
+	"azureApiKey":      "azure",
+	"geminiApiKey":     "gemini",
+	"groqApiKey":       "groq",
+	"mistralApiKey":    "mistral",
+	"openrouterApiKey": "openrouter",
+	"tgiApiKey":        "tgi",
+}
+
+// applyDeprecatedKeys moves the keys of deprecatedKeySettings in the config
+// file data into APIKeys, unless apiKeys sets the provider's key too
+func (c *Config) applyDeprecatedKeys(data []byte) {
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return
+	}
+	names := make([]string, 0, len(deprecatedKeySettings))
+	for name := range deprecatedKeySettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw, ok := settings[name]
+		if !ok {
+			continue
+		}
+		provider := deprecatedKeySettings[name]
+		var key string
+		if err := json.Unmarshal(raw, &key); err != nil {
+			c.loadErr = errors.Join(c.loadErr, fmt.Errorf("%s must be a string", name))
+			continue
+		}
+		c.deprecated = append(c.deprecated, fmt.Sprintf("%s sets %s, which is deprecated; use apiKeys.%s instead", c.source, name, provider))
+		if _, set := c.APIKeys[provider]; set || key == "" {
+			continue
+		}
+		keys := maps.Clone(c.APIKeys)
+		if keys == nil {
+			keys = map[string]string{}
+		}
+		keys[provider] = key
+		c.APIKeys = keys
+	}
+}
+
+// DeprecatedSettings returns warnings about deprecated settings the config
+// files still use
+func (c Config) DeprecatedSettings() []string {
+	return c.deprecated
+}
+
+// IgnoredSettings returns the settings of a repository config file that were
+// ignored because only ~/.ollama-commit.json may set them
+func (c Config) IgnoredSettings() []string {
+	return c.ignored
+}
+
+// Validate checks the configuration for invalid values and returns all the
+// problems found at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.loadErr != nil {
+		errs = append(errs, c.loadErr)
+	}
+
+	if parsed, err := url.Parse(c.OllamaAPIURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		errs = append(errs, fmt.Errorf("ollamaApiUrl %q is not a valid http(s) URL", c.OllamaAPIURL))
+	}
+	for name, template := range map[string]string{
+		"promptTemplate":        c.PromptTemplate,
+		"reviewPromptTemplate":  c.ReviewPromptTemplate,
+		"prPromptTemplate":      c.PRPromptTemplate,
+		"mergePromptTemplate":   c.MergePromptTemplate,
+		"explainPromptTemplate": c.ExplainPromptTemplate,
+	} {
+		if !strings.Contains(template, "%s") {
+			errs = append(errs, fmt.Errorf("%s must contain %%s where the changes are inserted", name))
+		}
+	}
+
+	if _, ok := providers[c.Provider]; c.Provider != "" && !ok {
+		errs = append(errs, unknownProviderError(c.Provider))
+	}
+	for name := range c.APIKeys {
+		if _, ok := providers[name]; !ok {
+			errs = append(errs, fmt.Errorf("apiKeys: %w", unknownProviderError(name)))
+		}
+	}
+	errs = append(errs, validateEnum("subjectSeparator", c.SubjectSeparator, SeparatorBlankLine, SeparatorNewline))
+	errs = append(errs, validateEnum("lineEnding", c.LineEnding, LineEndingLF, LineEndingCRLF, LineEndingAuto))
+	errs = append(errs, validateEnum("subjectCase", c.SubjectCase, SubjectCasePreserve, SubjectCaseSentence, SubjectCaseLower))
+
+	if c.FindRenames < 0 || c.FindRenames > 100 {
+		errs = append(errs, fmt.Errorf("findRenames must be a percentage between 0 and 100, got %d", c.FindRenames))
+	}
+	if c.ConnectTimeout < 0 || c.Timeout < 0 || c.StreamIdleTimeout < 0 || c.StreamFirstTokenTimeout < 0 {
+		errs = append(errs, fmt.Errorf("connectTimeout, timeout, streamIdleTimeout and streamFirstTokenTimeout can't be negative"))
+	}
+	if c.VerificationTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("verificationTimeout must be positive"))
+	}
+	if c.MinMessageLength < 0 || c.MaxEscalations < 0 {
+		errs = append(errs, fmt.Errorf("minMessageLength and maxEscalations can't be negative"))
+	}
+	if c.Retries < 0 || c.RateLimitRPS < 0 || c.MaxConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("retries, rateLimitRps and maxConcurrency can't be negative"))
+	}
+	if c.MaxBodyBullets < 0 || c.MaxMessageBytes < 0 || c.SizeWarnBytes < 0 || c.TwoStageThreshold < 0 {
+		errs = append(errs, fmt.Errorf("maxBodyBullets, maxMessageBytes, sizeWarnBytes and twoStageThreshold can't be negative"))
+	}
+	for model, options := range c.ModelDefaults {
+		errs = append(errs, options.validate("modelDefaults."+model+"."))
+	}
+	if c.BedrockRegion != "" && !awsRegionPattern.MatchString(c.BedrockRegion) {
+		errs = append(errs, fmt.Errorf("bedrockRegion %q is not an AWS region like us-east-1", c.BedrockRegion))
+	}
+	if c.TicketPattern != "" {
+		if _, err := regexp.Compile(c.TicketPattern); err != nil {
+			errs = append(errs, fmt.Errorf("ticketPattern is not a valid regular expression: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateEnum returns an error listing the valid values if value isn't one of them
+func validateEnum(field, value string, valid ...string) error {
+	for _, v := range valid {
+		if value == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q is not valid (valid values: %s)", field, value, strings.Join(valid, ", "))
+}
+
+// homeConfigPath returns the path of the config file in the home directory
+func homeConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".ollama-commit.json"), nil
+}
+
+// ExportConfig writes the configuration to path so it can be shared and
+// installed elsewhere with ImportConfig. API keys are left out.
+func ExportConfig(config Config, path string) error {
+	config.APIKeys = nil
+	configJSON, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to create config JSON: %w", err)
+	}
+	if err := WriteFileAtomic(path, append(configJSON, '\n'), configFileMode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// ImportConfig validates the config file at path and installs it as
+// ~/.ollama-commit.json, returning the installed path
+func ImportConfig(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := parseConfig(path, data).Validate(); err != nil {
+		return "", fmt.Errorf("invalid configuration in %s:\n%w", path, err)
+	}
+
+	configPath, err := homeConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(configPath, data, configFileMode); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	return configPath, nil
+}
+
+// SaveConfig writes the configuration to ~/.ollama-commit.json and returns its path
+func SaveConfig(config Config) (string, error) {
+	// Convert config to JSON
+	configJSON, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to create config JSON: %w", err)
+	}
+
+	// Write to home directory
+	configPath, err := homeConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(configPath, configJSON, configFileMode); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	return configPath, nil
+}
+
+// CheckModelAllowed returns an error if allowed is non-empty and doesn't contain model
+func CheckModelAllowed(model string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, name := range allowed {
+		if name == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("model %q is not allowed by the configuration (allowed: %s)", model, strings.Join(allowed, ", "))
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// over path, so readers never see a partially written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	// Make sure the data is on disk before it replaces the old file
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
 }
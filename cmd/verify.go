@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxVerificationSummary is the longest verification summary included in the prompt
+const maxVerificationSummary = 120
+
+// VerificationResult is the outcome of the configured verification command
+type VerificationResult struct {
+	Command string
+	Passed  bool
+	Summary string // Last line of output, or why the command didn't finish
+}
+
+// RunVerification runs a verification command such as a test suite or
+// linter, killing it after timeout
+func RunVerification(command string, timeout time.Duration) VerificationResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := shellCommand(ctx, command).CombinedOutput()
+	result := VerificationResult{Command: command, Passed: err == nil, Summary: lastLine(string(output))}
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Summary = fmt.Sprintf("timed out after %s", timeout)
+	}
+	if runes := []rune(result.Summary); len(runes) > maxVerificationSummary {
+		result.Summary = string(runes[:maxVerificationSummary]) + "..."
+	}
+	return result
+}
+
+// lastLine returns the last non-empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// Status returns "pass" or "fail"
+func (r VerificationResult) Status() string {
+	if r.Passed {
+		return "pass"
+	}
+	return "fail"
+}
+
+// Instruction describes the verification to the model
+func (r VerificationResult) Instruction() string {
+	instruction := fmt.Sprintf("The verification command `%s` was run on these changes; result: %s", r.Command, r.Status())
+	if r.Summary != "" {
+		instruction += fmt.Sprintf(" (%s)", r.Summary)
+	}
+	return instruction + ". Mention it in the body only if it is relevant."
+}
+
+// Trailer returns the commit trailer recording the verification result
+func (r VerificationResult) Trailer() string {
+	return "Tested: " + r.Status()
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
+)
+
+// command is a subcommand of the CLI. A command runs itself, groups further
+// subcommands like "config check", or both, like "models" and "models use".
+type command struct {
+	name        string
+	usage       string // Arguments shown after the command name in the help
+	summary     string
+	run         func(args []string)
+	subcommands []command
+}
+
+// commands lists the top-level subcommands. Without one, generate runs.
+var commands []command
+
+func init() {
+	commands = []command{
+		{
+			name:    "generate",
+			usage:   "[flags] [paths... | file.patch]",
+			summary: "Generate a commit message for the staged (or unstaged) changes, the default when no command is given",
+			run:     runGenerate,
+		},
+		{
+			name:    "explain",
+			usage:   "[flags] [paths...]",
+			summary: "Explain in plain English what the staged (or unstaged) changes do; takes the flags of generate",
+			run:     runExplain,
+		},
+		{
+			name:    "config",
+			usage:   "<command>",
+			summary: "Manage the configuration file",
+			subcommands: []command{
+				{name: "list", summary: "Print the effective value of every setting as JSON", run: runConfigList},
+				{name: "get", usage: "<key>", summary: "Print the effective value of a setting", run: runConfigGet},
+				{name: "set", usage: "<key> <value>", summary: "Set a setting in the config file; lists and objects are given as JSON", run: runConfigSet},
+				{name: "unset", usage: "<key>", summary: "Remove a setting from the config file so its default applies", run: runConfigUnset},
+				{name: "check", summary: "Validate the configuration", run: runConfigCheck},
+				{name: "init", summary: "Create a config file interactively", run: runConfigInit},
+				{name: "export", usage: "<file>", summary: "Write the effective configuration to a file to share it", run: runConfigExport},
+				{name: "import", usage: "<file>", summary: "Validate a shared config file and install it as ~/.ollama-commit.json", run: runConfigImport},
+			},
+		},
+		{
+			name:    "models",
+			usage:   "[use <name>]",
+			summary: "List the installed models with their size and parameters, marking the configured one",
+			run:     runModels,
+			subcommands: []command{
+				{name: "use", usage: "<name>", summary: "Make an installed model the default in the config file", run: runModelsUse},
+			},
+		},
+		{
+			name:    "benchmark",
+			usage:   "-models <a,b,...> [-y]",
+			summary: "Generate a message for the staged changes with several models, compare them and commit with one",
+			run:     runBenchmark,
+		},
+		{
+			name:    "serve",
+			usage:   "[-listen address]",
+			summary: "Serve an HTTP API generating commit messages for editors and scripts",
+			run:     runServe,
+		},
+		{
+			name:    "stats",
+			usage:   "[-repo]",
+			summary: "Summarize the local generation history: acceptance rate, latency and diff size per model",
+			run:     runStats,
+		},
+		{
+			name:    "history",
+			usage:   "<command>",
+			summary: "Browse the generated messages and commit one again",
+			subcommands: []command{
+				{name: "list", usage: "[-n count] [-all]", summary: "List the latest messages generated in this repository", run: runHistoryList},
+				{name: "search", usage: "[-n count] [-all] <term>", summary: "List the messages containing a term", run: runHistorySearch},
+				{name: "show", usage: "<id>", summary: "Print a message in full", run: runHistoryShow},
+				{name: "reuse", usage: "[-y] <id>", summary: "Commit the staged changes with a message from the history", run: runHistoryReuse},
+			},
+		},
+		{
+			name:    "hook",
+			usage:   "<command>",
+			summary: "Manage the prepare-commit-msg hook that fills in the message of 'git commit'",
+			subcommands: []command{
+				{name: "install", summary: "Install the hook, keeping an existing one and running it first", run: runHookInstall},
+				{name: "uninstall", summary: "Remove the hook and restore the one it replaced", run: runHookUninstall},
+				{name: "status", summary: "Report whether the hook is installed", run: runHookStatus},
+			},
+		},
+		{
+			name:    "doctor",
+			usage:   "[-safe-dir]",
+			summary: "Check git, the repository, the configuration, the Ollama API and the model, suggesting fixes",
+			run:     runDoctor,
+		},
+		{
+			name:    "self-update",
+			usage:   "[-check] [-force]",
+			summary: "Download the latest release from GitHub, verify its checksum and replace this executable",
+			run:     runSelfUpdate,
+		},
+		{
+			name:    "completion",
+			usage:   "<bash|zsh|fish|powershell>",
+			summary: "Print a shell completion script, completing -model with the installed models",
+			run:     runCompletion,
+		},
+		{
+			name:    "version",
+			usage:   "[-json]",
+			summary: "Print the version, commit, build date and Go version",
+			run:     runVersion,
+		},
+		{
+			name:    "help",
+			usage:   "[command...]",
+			summary: "Show help for a command",
+			run:     runHelp,
+		},
+	}
+}
+
+func main() {
+	// Remove temp files even if interrupted
+	cmd.HandleInterrupts()
+
+	// Anything that isn't a command (flags, paths) is passed on to generate
+	args := os.Args[1:]
+	if len(args) > 0 {
+		if c := findCommand(commands, args[0]); c != nil {
+			runCommand(*c, args[0], args[1:])
+			return
+		}
+	}
+	runGenerate(args)
+}
+
+// findCommand returns the command with the given name, or nil
+func findCommand(list []command, name string) *command {
+	for i := range list {
+		if list[i].name == name {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+// runCommand runs c, descending into its subcommands. path is the command
+// line leading to c, e.g. "config check", used in messages.
+func runCommand(c command, path string, args []string) {
+	if len(args) > 0 {
+		if sub := findCommand(c.subcommands, args[0]); sub != nil {
+			runCommand(*sub, path+" "+sub.name, args[1:])
+			return
+		}
+	}
+	if c.run != nil {
+		c.run(args)
+		return
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		printGroupUsage(c, path)
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q for %s\n\n", args[0], path)
+	printGroupUsage(c, path)
+	os.Exit(2)
+}
+
+// printGroupUsage lists the subcommands of a command group
+func printGroupUsage(c command, path string) {
+	fmt.Fprintf(os.Stderr, "Usage: ollama-commit %s <command>\n\n%s\n\nCommands:\n", path, c.summary)
+	printCommandList(c.subcommands)
+
+	// The changes to a file or directory named like the command may have been meant
+	if _, err := os.Stat(path); err == nil && !strings.Contains(path, " ") {
+		fmt.Fprintf(os.Stderr, "\nTo describe the changes to the path %s, run 'ollama-commit -- %s'\n", path, path)
+	}
+}
+
+// printCommandList prints the names and summaries of the commands, aligned
+func printCommandList(list []command) {
+	width := 0
+	for _, c := range list {
+		width = max(width, len(c.name))
+	}
+	for _, c := range list {
+		fmt.Fprintf(os.Stderr, "  %-*s  %s\n", width, c.name, c.summary)
+	}
+}
+
+// newFlagSet returns the flag set of a command, with help text built from
+// its usage and summary. path is the command line leading to it.
+func newFlagSet(c *command, path string) *flag.FlagSet {
+	fs := flag.NewFlagSet(path, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ollama-commit %s %s\n\n%s\n", path, c.usage, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(os.Stderr, "\nFlags:")
+			fs.PrintDefaults()
+		}
+		if len(c.subcommands) > 0 {
+			fmt.Fprintln(os.Stderr, "\nCommands:")
+			printCommandList(c.subcommands)
+		}
+		if c.name == "generate" {
+			fmt.Fprintln(os.Stderr, "\nOther commands:")
+			printCommandList(commands)
+		}
+	}
+	return fs
+}
+
+// runHelp prints the help of a command, or the list of commands
+func runHelp(args []string) {
+	fs := newFlagSet(findCommand(commands, "help"), "help")
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: ollama-commit [command] [flags]\n\nGenerate git commit messages with Ollama. Without a command, generate runs.\n\nCommands:\n")
+		printCommandList(commands)
+		fmt.Fprintln(os.Stderr, "\nRun 'ollama-commit help <command>' for the flags of a command.")
+		return
+	}
+
+	list, path := commands, ""
+	for i, name := range args {
+		path = strings.TrimSpace(path + " " + name)
+		c := findCommand(list, name)
+		if c == nil {
+			fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", path)
+			os.Exit(2)
+		}
+		if i < len(args)-1 {
+			list = c.subcommands
+			continue
+		}
+		if c.run == nil {
+			printGroupUsage(*c, path)
+			return
+		}
+		// Commands print their usage for -h
+		c.run([]string{"-h"})
+	}
+}
+
+// loadConfig loads the configuration, then lets environment variables
+// (optionally from .env) override it
+func loadConfig() cmd.Config {
+	config := cmd.LoadConfig()
+	for _, setting := range config.IgnoredSettings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s sets %s, which is only read from ~/.ollama-commit.json; ignoring it\n", config.Source(), setting)
+	}
+	for _, warning := range config.DeprecatedSettings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err := cmd.LoadDotEnv(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	config.ApplyEnv()
+	return config
+}
+
+// generatorConfig returns the library configuration for the model, backend
+// and requests set in config. The message cleanup is left to the caller,
+// since generate applies it to refined messages too.
+func generatorConfig(config cmd.Config) ollamacommit.Config {
+	genConfig := ollamacommit.Config{
+		APIURL:           config.OllamaAPIURL,
+		Provider:         config.Provider,
+		Model:            config.DefaultModel,
+		PromptTemplate:   config.PromptTemplate,
+		JSONSchema:       config.JSONSchema,
+		APIKeys:          config.APIKeys,
+		UntrustedAPIURL:  config.APIURLFromRepo(),
+		AzureDeployment:  config.AzureDeployment,
+		AzureAPIVersion:  config.AzureAPIVersion,
+		BedrockRegion:    config.BedrockRegion,
+		ResponseFields:   config.ResponseFields,
+		AllowedHosts:     config.AllowedHosts,
+		ConnectTimeout:   time.Duration(config.ConnectTimeout) * time.Second,
+		Timeout:          time.Duration(config.Timeout) * time.Second,
+		Retries:          config.Retries,
+		RetryOnRateLimit: config.RetryOnRateLimit,
+		RateLimit:        config.RateLimitRPS,
+	}
+	if options, ok := config.ModelDefaults[config.DefaultModel]; ok {
+		genConfig.ModelOptions = &options
+	}
+	if config.Stream {
+		genConfig.StreamIdleTimeout = time.Duration(config.StreamIdleTimeout) * time.Second
+		genConfig.StreamFirstTokenTimeout = time.Duration(config.StreamFirstTokenTimeout) * time.Second
+		genConfig.OnStall = func(idle time.Duration) {
+			fmt.Fprintf(os.Stderr, "Warning: model output stalled for %s; using the partial message\n", idle)
+		}
+	}
+	return genConfig
+}
+
+// newGenerator creates the generator, exiting if the configuration is invalid
+func newGenerator(genConfig ollamacommit.Config) *ollamacommit.Generator {
+	generator, err := ollamacommit.New(genConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if genConfig.UntrustedAPIURL && !cmd.IsLocalURL(genConfig.APIURL) {
+		fmt.Fprintf(os.Stderr, "Warning: the repository sets the API URL %s; API keys are only sent to the providers' own hosts\n", genConfig.APIURL)
+	}
+	if genConfig.StreamIdleTimeout > 0 && !cmd.CanStream(generator.Provider()) {
+		fmt.Fprintf(os.Stderr, "Warning: the %s provider doesn't stream its responses; ignoring -stream\n", generator.Provider().Name())
+	}
+	return generator
+}
+
+// parseArgs parses the flags of a command that takes exactly n positional arguments
+func parseArgs(c *command, path string, args []string, n int) *flag.FlagSet {
+	fs := newFlagSet(c, path)
+	fs.Parse(args)
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs
+}
+
+// configCommand returns the config subcommand with the given name
+func configCommand(name string) *command {
+	return findCommand(findCommand(commands, "config").subcommands, name)
+}
+
+func runConfigCheck(args []string) {
+	parseArgs(configCommand("check"), "config check", args, 0)
+	config := loadConfig()
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Configuration is valid")
+}
+
+func runConfigInit(args []string) {
+	parseArgs(configCommand("init"), "config init", args, 0)
+	config := loadConfig()
+	initConfigFile(config, modelsGenerator(config).Client())
+}
+
+func runConfigExport(args []string) {
+	fs := parseArgs(configCommand("export"), "config export", args, 1)
+	exportConfigFile(loadConfig(), fs.Arg(0))
+}
+
+func runConfigImport(args []string) {
+	fs := parseArgs(configCommand("import"), "config import", args, 1)
+	installConfig(fs.Arg(0))
+}
+
+// initConfigFile creates a configuration interactively, starting from config
+// and probing the server with client
+func initConfigFile(config cmd.Config, client *cmd.Client) {
+	newConfig, err := cmd.RunInit(config, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	configPath, err := cmd.SaveConfig(newConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration saved to %s\n", configPath)
+}
+
+// exportConfigFile writes config to path so it can be shared
+func exportConfigFile(config cmd.Config, path string) {
+	if err := cmd.ExportConfig(config, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error exporting config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration exported to %s\n", path)
+}
+
+// installConfig validates a shared config file and installs it as the user's configuration
+func installConfig(path string) {
+	configPath, err := cmd.ImportConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing config: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration installed to %s\n", configPath)
+}
+
+func runConfigGet(args []string) {
+	fs := parseArgs(configCommand("get"), "config get", args, 1)
+	value, err := cmd.ConfigValue(loadConfig(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print strings as they are, so templates keep their line breaks
+	var text string
+	if err := json.Unmarshal([]byte(value), &text); err == nil {
+		value = text
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(args []string) {
+	fs := parseArgs(configCommand("set"), "config set", args, 2)
+	path, err := cmd.EditableConfigPath(cmd.LoadConfig(), fs.Arg(0))
+	if err == nil {
+		err = cmd.SetConfigValue(path, fs.Arg(0), fs.Arg(1))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Set %s in %s\n", fs.Arg(0), path)
+}
+
+func runConfigUnset(args []string) {
+	fs := parseArgs(configCommand("unset"), "config unset", args, 1)
+	path, err := cmd.EditableConfigPath(cmd.LoadConfig(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	removed, err := cmd.UnsetConfigValue(path, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !removed {
+		fmt.Printf("%s is not set in %s\n", fs.Arg(0), path)
+		return
+	}
+	fmt.Printf("Removed %s from %s; the default applies again\n", fs.Arg(0), path)
+}
+
+func runConfigList(args []string) {
+	parseArgs(configCommand("list"), "config list", args, 0)
+	config := loadConfig()
+	for _, key := range cmd.ConfigKeys() {
+		value, err := cmd.ConfigValue(config, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s = %s\n", key, value)
+	}
+}
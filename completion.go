@@ -0,0 +1,291 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
+)
+
+// completionShells are the shells a completion script can be printed for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// completionFlag is a flag of generate offered by the completion scripts
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool // Takes no value
+}
+
+// completionArgs are the words completed after a command, e.g. the
+// subcommands of a group
+type completionArgs struct {
+	command string
+	words   []string
+}
+
+// completionData is what the completion scripts offer
+type completionData struct {
+	commands []command
+	args     []completionArgs
+	flags    []completionFlag
+}
+
+func runCompletion(args []string) {
+	c := findCommand(commands, "completion")
+	fs := newFlagSet(c, "completion")
+	listModels := fs.Bool("models", false, "List the installed models, one per line, for the scripts to complete -model")
+	fs.Parse(args)
+
+	if *listModels {
+		printModelNames()
+		return
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	data := gatherCompletionData()
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Print(bashCompletion(data))
+	case "zsh":
+		fmt.Print(zshCompletion(data))
+	case "fish":
+		fmt.Print(fishCompletion(data))
+	case "powershell":
+		fmt.Print(powershellCompletion(data))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell %q; use one of %s\n", fs.Arg(0), strings.Join(completionShells, ", "))
+		os.Exit(2)
+	}
+}
+
+// printModelNames prints the models installed on the configured server.
+// It is called while the user presses tab, so it gives up quickly and
+// prints nothing on errors.
+func printModelNames() {
+	config := loadConfig()
+	genConfig := generatorConfig(config)
+	genConfig.ConnectTimeout, genConfig.Timeout = time.Second, 2*time.Second
+	generator, err := ollamacommit.New(genConfig)
+	if err != nil {
+		return
+	}
+	provider := generator.Provider()
+	if !cmd.CanListModels(provider) || cmd.CheckHostAllowed(cmd.ProviderURL(provider, config.OllamaAPIURL), config.AllowedHosts) != nil {
+		return
+	}
+
+	models, err := cmd.ListModels(provider)
+	if err != nil {
+		return
+	}
+	for _, model := range models {
+		fmt.Println(model.Name)
+	}
+}
+
+// gatherCompletionData collects the commands and the flags of generate
+func gatherCompletionData() completionData {
+	data := completionData{commands: commands}
+
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	for _, c := range commands {
+		switch {
+		case len(c.subcommands) > 0:
+			var words []string
+			for _, sub := range c.subcommands {
+				words = append(words, sub.name)
+			}
+			data.args = append(data.args, completionArgs{c.name, words})
+		case c.name == "completion":
+			data.args = append(data.args, completionArgs{c.name, completionShells})
+		case c.name == "help":
+			data.args = append(data.args, completionArgs{c.name, names})
+		}
+	}
+
+	// Only the names and usages matter, so the built-in defaults will do
+	fs, _ := newGenerateFlags(cmd.DefaultConfig())
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		data.flags = append(data.flags, completionFlag{f.Name, f.Usage, ok && boolFlag.IsBoolFlag()})
+	})
+
+	return data
+}
+
+// commandNames returns the names of the commands, generate included unless
+// only the other commands are wanted
+func (d completionData) commandNames(withGenerate bool) []string {
+	var names []string
+	for _, c := range d.commands {
+		if withGenerate || c.name != "generate" {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// flagNames returns the flags of generate with their leading dash
+func (d completionData) flagNames() []string {
+	names := make([]string, len(d.flags))
+	for i, f := range d.flags {
+		names[i] = "-" + f.name
+	}
+	return names
+}
+
+func bashCompletion(d completionData) string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for ollama-commit
+# Load it with: source <(ollama-commit completion bash)
+_ollama_commit() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        -model|--model)
+            COMPREPLY=($(compgen -W "$(ollama-commit completion -models 2>/dev/null)" -- "$cur"))
+            return
+            ;;
+    esac
+`)
+	fmt.Fprintf(&b, `    if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur") $(compgen -f -- "$cur"))
+        return
+    fi
+    if [[ $COMP_CWORD -eq 2 ]]; then
+        case "${COMP_WORDS[1]}" in
+`, strings.Join(d.commandNames(true), " "))
+	for _, args := range d.args {
+		fmt.Fprintf(&b, "            %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", args.command, strings.Join(args.words, " "))
+	}
+	fmt.Fprintf(&b, `        esac
+    fi
+    case "${COMP_WORDS[1]}" in
+        %s) return ;;
+    esac
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o filenames -F _ollama_commit ollama-commit
+`, strings.Join(d.commandNames(false), "|"), strings.Join(d.flagNames(), " "))
+	return b.String()
+}
+
+func zshCompletion(d completionData) string {
+	var b strings.Builder
+	b.WriteString(`#compdef ollama-commit
+# Load it with: source <(ollama-commit completion zsh)
+_ollama_commit() {
+    local cur=${words[CURRENT]} prev=${words[CURRENT-1]}
+    case $prev in
+        -model|--model)
+            compadd -- ${(f)"$(ollama-commit completion -models 2>/dev/null)"}
+            return
+            ;;
+    esac
+`)
+	fmt.Fprintf(&b, `    if (( CURRENT == 2 )) && [[ $cur != -* ]]; then
+        compadd -- %s
+        _files
+        return
+    fi
+    if (( CURRENT == 3 )); then
+        case ${words[2]} in
+`, strings.Join(d.commandNames(true), " "))
+	for _, args := range d.args {
+		fmt.Fprintf(&b, "            %s) compadd -- %s; return ;;\n", args.command, strings.Join(args.words, " "))
+	}
+	fmt.Fprintf(&b, `        esac
+    fi
+    case ${words[2]} in
+        %s) return ;;
+    esac
+    if [[ $cur == -* ]]; then
+        compadd -- %s
+        return
+    fi
+    _files
+}
+compdef _ollama_commit ollama-commit
+`, strings.Join(d.commandNames(false), "|"), strings.Join(d.flagNames(), " "))
+	return b.String()
+}
+
+// fishQuote quotes s for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func fishCompletion(d completionData) string {
+	var b strings.Builder
+	b.WriteString("# fish completion for ollama-commit\n# Load it with: ollama-commit completion fish | source\n")
+	for _, c := range d.commands {
+		fmt.Fprintf(&b, "complete -c ollama-commit -n __fish_use_subcommand -f -a %s -d %s\n", c.name, fishQuote(c.summary))
+	}
+	for _, args := range d.args {
+		fmt.Fprintf(&b, "complete -c ollama-commit -n '__fish_seen_subcommand_from %s' -f -a %s\n", args.command, fishQuote(strings.Join(args.words, " ")))
+	}
+
+	// Flags belong to generate, whether or not it is named
+	generating := fishQuote("not __fish_seen_subcommand_from " + strings.Join(d.commandNames(false), " "))
+	for _, f := range d.flags {
+		switch {
+		case f.name == "model":
+			fmt.Fprintf(&b, "complete -c ollama-commit -n %s -o model -x -a '(ollama-commit completion -models 2>/dev/null)' -d %s\n", generating, fishQuote(f.usage))
+		case f.isBool:
+			fmt.Fprintf(&b, "complete -c ollama-commit -n %s -o %s -d %s\n", generating, f.name, fishQuote(f.usage))
+		default:
+			fmt.Fprintf(&b, "complete -c ollama-commit -n %s -o %s -r -d %s\n", generating, f.name, fishQuote(f.usage))
+		}
+	}
+	return b.String()
+}
+
+// powershellList formats words as a PowerShell array
+func powershellList(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = "'" + strings.ReplaceAll(word, "'", "''") + "'"
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+func powershellCompletion(d completionData) string {
+	var b strings.Builder
+	b.WriteString(`# PowerShell completion for ollama-commit
+# Load it with: ollama-commit completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName ollama-commit -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '') { $words = @($words | Select-Object -SkipLast 1) }
+    $prev = if ($words.Count -gt 0) { $words[-1] } else { '' }
+    $candidates = @()
+    if ($prev -eq '-model' -or $prev -eq '--model') {
+        $candidates = @(ollama-commit completion -models 2>$null)
+    }
+`)
+	fmt.Fprintf(&b, "    elseif ($words.Count -eq 0 -and -not $wordToComplete.StartsWith('-')) {\n        $candidates = %s\n    }\n", powershellList(d.commandNames(true)))
+	for _, args := range d.args {
+		fmt.Fprintf(&b, "    elseif ($words.Count -eq 1 -and $words[0] -eq '%s') {\n        $candidates = %s\n    }\n", args.command, powershellList(args.words))
+	}
+	fmt.Fprintf(&b, "    elseif ($wordToComplete.StartsWith('-') -and %s -notcontains $words[0]) {\n        $candidates = %s\n    }\n", powershellList(d.commandNames(false)), powershellList(d.flagNames()))
+	b.WriteString(`    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
+)
+
+func runDoctor(args []string) {
+	fs := newFlagSet(findCommand(commands, "doctor"), "doctor")
+	safeDir := fs.Bool("safe-dir", false, "Let git use the repository even if it is owned by another user")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config := loadConfig()
+	if *safeDir {
+		cmd.TrustAllDirectories()
+	}
+	genConfig := generatorConfig(config)
+	genConfig.ConnectTimeout, genConfig.Timeout = 5*time.Second, 10*time.Second
+	generator, err := ollamacommit.New(genConfig)
+	if err != nil {
+		// The configuration check reports the problem; reach the API as well
+		// as possible for the other checks
+		genConfig.Provider, genConfig.ModelOptions = "", nil
+		generator = newGenerator(genConfig)
+	}
+
+	failed := 0
+	for _, check := range cmd.RunDoctor(config, generator.Provider()) {
+		status := "OK  "
+		if !check.OK {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s %s: %s\n", status, check.Name, strings.ReplaceAll(check.Detail, "\n", "\n     "))
+		if check.Fix != "" {
+			fmt.Printf("     Fix: %s\n", check.Fix)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d problem(s) found\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\nEverything looks good")
+}
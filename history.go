@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+// historyCommand returns the history subcommand with the given name
+func historyCommand(name string) *command {
+	return findCommand(findCommand(commands, "history").subcommands, name)
+}
+
+// loadHistory reads the recorded generations, exiting on errors
+func loadHistory() []cmd.GenerationRecord {
+	records, err := cmd.LoadGenerations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return records
+}
+
+// printHistory prints one line per message, newest first
+func printHistory(history []cmd.GenerationRecord, limit int) {
+	if len(history) == 0 {
+		fmt.Println("No messages found")
+		return
+	}
+	if limit > 0 && len(history) > limit {
+		history = history[:limit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tOUTCOME\tMODEL\tSUBJECT")
+	for _, record := range history {
+		subject, _ := cmd.SplitMessage(record.Message, "")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.ID, record.Time.Local().Format("2006-01-02 15:04"), record.Outcome, record.Model, subject)
+	}
+	w.Flush()
+}
+
+func runHistoryList(args []string) {
+	fs := newFlagSet(historyCommand("list"), "history list")
+	limit := fs.Int("n", 20, "Number of messages to show, 0 for all")
+	all := fs.Bool("all", false, "Show the messages of every repository, not just the current one")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	repo := ""
+	if !*all {
+		repo = cmd.CurrentRepo()
+	}
+	printHistory(cmd.History(loadHistory(), repo, ""), *limit)
+}
+
+func runHistorySearch(args []string) {
+	fs := newFlagSet(historyCommand("search"), "history search")
+	limit := fs.Int("n", 0, "Number of messages to show, 0 for all")
+	all := fs.Bool("all", false, "Search the messages of every repository, not just the current one")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	repo := ""
+	if !*all {
+		repo = cmd.CurrentRepo()
+	}
+	printHistory(cmd.History(loadHistory(), repo, fs.Arg(0)), *limit)
+}
+
+func runHistoryShow(args []string) {
+	fs := parseArgs(historyCommand("show"), "history show", args, 1)
+	record, err := cmd.FindGeneration(loadHistory(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("ID:      %s\nDate:    %s\nRepo:    %s\nModel:   %s\nOutcome: %s\n\n%s\n",
+		record.ID, record.Time.Local().Format("2006-01-02 15:04:05"), record.Repo, record.Model, record.Outcome, record.Message)
+}
+
+func runHistoryReuse(args []string) {
+	fs := newFlagSet(historyCommand("reuse"), "history reuse")
+	noConfirm := fs.Bool("y", false, "Commit without asking for confirmation")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	record, err := cmd.FindGeneration(loadHistory(), fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if repo := cmd.CurrentRepo(); repo != record.Repo {
+		fmt.Fprintf(os.Stderr, "Warning: the message was generated in %s\n", record.Repo)
+	} else if diff, err := cmd.GetGitDiff(cmd.DiffOptions{Exclude: loadConfig().GeneratedFiles}); err == nil && cmd.DiffHash(diff) != record.DiffHash {
+		fmt.Fprintln(os.Stderr, "Warning: the changes differ from the ones the message was generated for")
+	}
+
+	// Commit it the way -reuse-last commits the previous run's message
+	if err := cmd.SaveLastMessage(record.Message); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	generateArgs := []string{"-reuse-last"}
+	if *noConfirm {
+		generateArgs = append(generateArgs, "-y")
+	}
+	runGenerate(generateArgs)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+// hookCommand returns the hook subcommand with the given name
+func hookCommand(name string) *command {
+	return findCommand(findCommand(commands, "hook").subcommands, name)
+}
+
+func runHookInstall(args []string) {
+	parseArgs(hookCommand("install"), "hook install", args, 0)
+	executable, err := os.Executable()
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+			executable = resolved
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to find the executable: %v\n", err)
+		os.Exit(1)
+	}
+
+	status, err := cmd.InstallHook(executable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	fmt.Printf("Installed the %s hook in %s\n", cmd.HookName, status.Path)
+	if status.Chained != "" {
+		fmt.Printf("The previous hook, now %s, runs first\n", status.Chained)
+	}
+}
+
+func runHookUninstall(args []string) {
+	parseArgs(hookCommand("uninstall"), "hook uninstall", args, 0)
+	status, err := cmd.GetHookStatus()
+	if err == nil {
+		var removed bool
+		if removed, err = cmd.UninstallHook(); err == nil && !removed {
+			fmt.Println("The hook is not installed")
+			return
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	fmt.Printf("Removed the %s hook from %s\n", cmd.HookName, status.Path)
+	if status.Chained != "" {
+		fmt.Printf("Restored the previous hook from %s\n", status.Chained)
+	}
+}
+
+func runHookStatus(args []string) {
+	parseArgs(hookCommand("status"), "hook status", args, 0)
+	status, err := cmd.GetHookStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	switch {
+	case status.Installed:
+		fmt.Printf("Installed: %s\n", status.Path)
+		if status.Chained != "" {
+			fmt.Printf("Runs first: %s\n", status.Chained)
+		}
+	case status.Foreign:
+		fmt.Printf("Not installed; %s is another hook, which 'hook install' will keep and run first\n", status.Path)
+	default:
+		fmt.Printf("Not installed; 'hook install' will write %s\n", status.Path)
+	}
+}
@@ -1,99 +1,1201 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
 )
 
-func main() {
-	// Load configuration
-	config := cmd.LoadConfig()
-
-	// Define flags with defaults from config
-	autoCommit := flag.Bool("a", false, "Automatically commit using the generated message")
-	model := flag.String("model", config.DefaultModel, "Ollama model to use")
-	noConfirm := flag.Bool("y", false, "Skip confirmation prompt")
-	saveConfig := flag.Bool("save-config", false, "Save current settings to config file")
-	ollamaURL := flag.String("url", config.OllamaAPIURL, "Ollama API URL")
-	flag.Parse()
-
-	// Save configuration if requested
-	if *saveConfig {
-		config.DefaultModel = *model
-		config.OllamaAPIURL = *ollamaURL
-
-		// Convert config to JSON
-		configJSON, err := json.MarshalIndent(config, "", "  ")
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Exit codes for errors scripts may want to tell apart
+const (
+	exitError          = 1
+	exitNoChanges      = 2
+	exitNotARepo       = 3
+	exitAPIUnreachable = 4
+	exitModelNotFound  = 5
+)
+
+// exitCode returns the exit code for an error
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, cmd.ErrNoChanges):
+		return exitNoChanges
+	case errors.Is(err, cmd.ErrNotARepo):
+		return exitNotARepo
+	case errors.Is(err, cmd.ErrAPIUnreachable):
+		return exitAPIUnreachable
+	case errors.Is(err, cmd.ErrModelNotFound):
+		return exitModelNotFound
+	}
+	return exitError
+}
+
+// printMessage prints a commit message between separator lines
+func printMessage(header, message string) {
+	fmt.Println(header)
+	fmt.Println("------------------------")
+	fmt.Println(message)
+	fmt.Println("------------------------")
+}
+
+// isPatchPath reports whether a path names a patch file by its extension
+func isPatchPath(path string) bool {
+	return strings.HasSuffix(path, ".patch") || strings.HasSuffix(path, ".diff")
+}
+
+// pathspecsForced reports whether the positional arguments of a command line
+// came after --, which makes them pathspecs whatever they look like
+func pathspecsForced(args, positional []string) bool {
+	i := len(args) - len(positional) - 1
+	return i >= 0 && args[i] == "--"
+}
+
+// deprecatedFlags maps the flags of generate that became commands to those commands
+var deprecatedFlags = map[string]string{
+	"export-config": "config export",
+	"import-config": "config import",
+	"version":       "version",
+	"check-config":  "config check",
+	"init":          "config init",
+}
+
+// warnDeprecatedFlags warns about each deprecated flag that was given
+func warnDeprecatedFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		if replacement, ok := deprecatedFlags[f.Name]; ok {
+			fmt.Fprintf(os.Stderr, "Warning: -%s is deprecated and will be removed; use 'ollama-commit %s' instead\n", f.Name, replacement)
+		}
+	})
+}
+
+// runExplain explains the changes instead of generating a commit message,
+// taking the same flags and paths as generate
+func runExplain(args []string) {
+	runGenerate(append([]string{"-explain"}, args...))
+}
+
+// generateFlags holds the flags of generate
+type generateFlags struct {
+	autoCommit              bool
+	model                   string
+	noConfirm               bool
+	force                   bool
+	saveConfig              bool
+	exportConfig            string
+	importConfig            string
+	showVersion             bool
+	checkConfig             bool
+	initConfig              bool
+	ollamaURL               string
+	provider                string
+	templateName            string
+	listTemplates           bool
+	findRenames             int
+	reuseLast               bool
+	findCopies              bool
+	plumbing                bool
+	ignoreWhitespace        bool
+	baseRef                 string
+	connectTimeout          int
+	timeout                 int
+	review                  bool
+	explain                 bool
+	outputPath              string
+	quiet                   bool
+	deterministic           bool
+	temperature             float64
+	maxTokens               int
+	conventionsFile         string
+	offline                 bool
+	interactiveRefine       bool
+	batchFile               string
+	prMode                  bool
+	prBase                  string
+	safeDir                 bool
+	submoduleContext        bool
+	verbose                 bool
+	dryRun                  bool
+	showSent                bool
+	stream                  bool
+	patchFile               string
+	enforceImperative       bool
+	subjectCase             string
+	twoStage                bool
+	compare                 string
+	lang                    string
+	hint                    string
+	nameStatus              bool
+	previewLog              bool
+	separateCommits         bool
+	retryOnRateLimit        bool
+	includeGenerated        bool
+	warnTodo                bool
+	strictTodo              bool
+	addNote                 bool
+	verifyCommit            bool
+	transcode               bool
+	streamIdleTimeout       int
+	streamFirstTokenTimeout int
+	jsonSchema              bool
+	coAuthors               stringList
+}
+
+// newGenerateFlags defines the flags of generate, with defaults from config
+func newGenerateFlags(config cmd.Config) (*flag.FlagSet, *generateFlags) {
+	f := &generateFlags{}
+	fs := newFlagSet(findCommand(commands, "generate"), "generate")
+	fs.BoolVar(&f.autoCommit, "a", false, "Automatically commit using the generated message")
+	fs.StringVar(&f.model, "model", config.DefaultModel, "Ollama model to use")
+	fs.BoolVar(&f.noConfirm, "y", false, "Skip confirmation prompt")
+	fs.BoolVar(&f.force, "force", false, "Commit even if the changes were modified while the message was generated, or only trivial changes remain after filters")
+	fs.BoolVar(&f.saveConfig, "save-config", false, "Save current settings to config file")
+	fs.StringVar(&f.exportConfig, "export-config", "", "Deprecated: use 'ollama-commit config export'")
+	fs.StringVar(&f.importConfig, "import-config", "", "Deprecated: use 'ollama-commit config import'")
+	fs.BoolVar(&f.showVersion, "version", false, "Deprecated: use 'ollama-commit version'")
+	fs.BoolVar(&f.checkConfig, "check-config", false, "Deprecated: use 'ollama-commit config check'")
+	fs.BoolVar(&f.initConfig, "init", false, "Deprecated: use 'ollama-commit config init'")
+	fs.StringVar(&f.ollamaURL, "url", config.OllamaAPIURL, "Ollama API URL")
+	fs.StringVar(&f.provider, "provider", config.Provider, "Backend to send requests to: "+strings.Join(cmd.ProviderNames(), ", ")+"; detected from -url if empty")
+	fs.StringVar(&f.templateName, "template", "", "Built-in prompt template to use (see -list-templates)")
+	fs.BoolVar(&f.listTemplates, "list-templates", false, "List the built-in prompt templates")
+	fs.IntVar(&f.findRenames, "find-renames", config.FindRenames, "Rename detection similarity threshold in percent (0 uses git's default)")
+	fs.BoolVar(&f.reuseLast, "reuse-last", false, "Commit using the last generated message without calling the model")
+	fs.BoolVar(&f.findCopies, "find-copies", config.FindCopies, "Detect copied files as well as renames")
+	fs.BoolVar(&f.plumbing, "plumbing", config.Plumbing, "Gather the diff with git plumbing commands (diff-index/diff-files)")
+	fs.BoolVar(&f.ignoreWhitespace, "ignore-whitespace", config.IgnoreWhitespace, "Leave whitespace-only changes out of the diff")
+	fs.StringVar(&f.baseRef, "base", "", "Ref to diff against instead of HEAD")
+	fs.IntVar(&f.connectTimeout, "connect-timeout", config.ConnectTimeout, "Seconds to wait for a connection to the Ollama API")
+	fs.IntVar(&f.timeout, "timeout", config.Timeout, "Seconds to wait for the generation to finish (0 means no limit)")
+	fs.BoolVar(&f.review, "review", false, "Review the changes for potential issues instead of generating a commit message")
+	fs.BoolVar(&f.explain, "explain", false, "Explain the changes in plain English instead of generating a commit message")
+	fs.StringVar(&f.outputPath, "o", "", "Write the generated message to this file")
+	fs.StringVar(&f.outputPath, "output", "", "Write the generated message to this file (same as -o)")
+	fs.BoolVar(&f.quiet, "quiet", false, "Don't print the generated message to stdout")
+	fs.BoolVar(&f.deterministic, "deterministic", false, "Use a fixed seed, temperature 0 and top_p 1 for reproducible output")
+	fs.Float64Var(&f.temperature, "temperature", 0, "Sampling temperature, overriding the model's default and modelDefaults")
+	fs.IntVar(&f.maxTokens, "max-tokens", 0, "Most tokens the model may generate, overriding modelDefaults")
+	fs.StringVar(&f.conventionsFile, "conventions-file", config.ConventionsFile, "File with project commit conventions to add to the prompt")
+	fs.BoolVar(&f.offline, "offline", false, "Generate a simple message from the changed files without calling the model")
+	fs.BoolVar(&f.interactiveRefine, "interactive-refine", false, "Refine the generated message with the model by typing instructions until you accept it")
+	fs.StringVar(&f.batchFile, "batch", "", "Run in each repository listed in this file (one path per line)")
+	fs.BoolVar(&f.prMode, "pr", false, "Generate a pull request title and description for the current branch instead of a commit message")
+	fs.StringVar(&f.prBase, "pr-base", "", "Base branch for -pr (default origin/HEAD, main or master)")
+	fs.BoolVar(&f.safeDir, "safe-dir", false, "Let git use the repository even if it is owned by another user (safe.directory=*)")
+	fs.BoolVar(&f.submoduleContext, "submodule-context", false, "Include the commits pulled in by submodule updates in the prompt")
+	fs.BoolVar(&f.verbose, "v", false, "Print extra information such as token estimates to stderr")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Print the estimated prompt size (and cost if pricePerToken is set) without calling the model")
+	fs.BoolVar(&f.showSent, "show-sent", false, "Print a summary of what was sent to the model to stderr")
+	fs.BoolVar(&f.stream, "stream", config.Stream, "Stream the response and cancel the generation if the model stalls")
+	fs.StringVar(&f.patchFile, "patch-file", "", "Describe the changes in a .patch or .diff file instead of the repository's")
+	fs.BoolVar(&f.enforceImperative, "enforce-imperative", false, "Rewrite subjects starting with a past tense verb (\"Added\") in imperative mood (\"Add\")")
+	fs.StringVar(&f.subjectCase, "subject-case", config.SubjectCase, "Case of the subject's first letter: preserve, sentence or lower")
+	fs.BoolVar(&f.twoStage, "two-stage", false, "For large diffs, have the model summarize the changes first and write the message from the summary")
+	fs.StringVar(&f.compare, "compare", "", "Generate a message with each of two comma-separated models and print them side by side, without committing")
+	fs.StringVar(&f.lang, "lang", config.Language, "Language of the commit message, or \"auto\" to match the comments in the diff")
+	fs.StringVar(&f.hint, "hint", "", "One-line description of the intent of the changes, given to the model")
+	fs.BoolVar(&f.nameStatus, "name-status", config.IncludeNameStatus, "Send the name-status list of changed files along with the diff")
+	fs.BoolVar(&f.previewLog, "preview-log", false, "Show the message the way git log --oneline and git log will display it")
+	fs.BoolVar(&f.separateCommits, "commit-all-separate", false, "Commit each staged file separately with its own message, asking for each unless -y")
+	fs.BoolVar(&f.retryOnRateLimit, "retry-on-rate-limit", config.RetryOnRateLimit, "Retry requests rejected with 429 after the delay given by the server")
+	fs.BoolVar(&f.includeGenerated, "include-generated", config.IncludeGenerated, "Keep lock and generated files (see generatedFiles) in the diff")
+	fs.BoolVar(&f.warnTodo, "warn-todo", config.WarnTodo, "Warn about TODO, FIXME and XXX markers in the added lines")
+	fs.BoolVar(&f.strictTodo, "strict-todo", false, "Like -warn-todo, but stop if any markers are found")
+	fs.BoolVar(&f.addNote, "add-note", config.AddNote, "Attach a git note to the commit recording the model and prompt that generated the message")
+	fs.BoolVar(&f.verifyCommit, "verify-commit", false, "Check that the committed message matches the generated one, e.g. after commit-msg hooks")
+	fs.BoolVar(&f.transcode, "transcode", config.TranscodeMessage, "Convert the commit message to the repository's i18n.commitEncoding")
+	fs.IntVar(&f.streamIdleTimeout, "stream-idle-timeout", config.StreamIdleTimeout, "Seconds without a new token before a streamed generation is cancelled")
+	fs.IntVar(&f.streamFirstTokenTimeout, "stream-first-token-timeout", config.StreamFirstTokenTimeout, "Seconds a streamed generation may take to produce its first token, including loading the model (0 means no limit)")
+	fs.BoolVar(&f.jsonSchema, "json-schema", false, "Request a structured JSON commit object using Ollama's format parameter")
+	fs.Var(&f.coAuthors, "co-author", "Add a Co-authored-by trailer (alias from authorMap or \"Name <email>\", repeatable)")
+	return fs, f
+}
+
+// runGenerate generates a commit message for the changes, the default command
+func runGenerate(args []string) {
+	config := loadConfig()
+
+	fs, flags := newGenerateFlags(config)
+	fs.Parse(args)
+	warnDeprecatedFlags(fs)
+
+	// A lone .patch or .diff argument is a patch to describe, unless given
+	// after -- to limit the changes to that file like any other pathspec
+	paths := fs.Args()
+	if len(paths) == 1 && flags.patchFile == "" && isPatchPath(paths[0]) && !pathspecsForced(args, paths) {
+		flags.patchFile, paths = paths[0], nil
+	}
+
+	if flags.showVersion {
+		fmt.Println(versionString())
+		os.Exit(0)
+	}
+
+	// Install a shared configuration, validated before replacing the current one
+	if flags.importConfig != "" {
+		installConfig(flags.importConfig)
+		os.Exit(0)
+	}
+
+	// Model parameters, left to the server's defaults unless a profile is selected
+	var options *cmd.Options
+	if flags.deterministic {
+		options = cmd.DeterministicOptions()
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "temperature" {
+			options = cmd.MergeOptions(options, &cmd.Options{Temperature: &flags.temperature})
+		}
+		if f.Name == "max-tokens" {
+			options = cmd.MergeOptions(options, &cmd.Options{MaxTokens: &flags.maxTokens})
+		}
+	})
+
+	// Per-model defaults from the config, with the flags above taking precedence
+	optionsFor := func(model string) *cmd.Options {
+		if defaults, ok := config.ModelDefaults[model]; ok {
+			return cmd.MergeOptions(&defaults, options)
+		}
+		return options
+	}
+
+	// Fail fast on configuration mistakes in the values in effect after the
+	// flags (unless about to replace the configuration)
+	config.OllamaAPIURL = flags.ollamaURL
+	config.Provider = flags.provider
+	config.FindRenames = flags.findRenames
+	config.ConnectTimeout = flags.connectTimeout
+	config.Timeout = flags.timeout
+	config.StreamIdleTimeout = flags.streamIdleTimeout
+	config.StreamFirstTokenTimeout = flags.streamFirstTokenTimeout
+	config.Stream = flags.stream
+	config.RetryOnRateLimit = flags.retryOnRateLimit
+	config.SubjectCase = flags.subjectCase
+	err := config.Validate()
+	if options != nil {
+		err = errors.Join(err, options.Validate())
+	}
+	if err != nil && !flags.initConfig {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if flags.checkConfig {
+		fmt.Println("Configuration is valid")
+		os.Exit(0)
+	}
+
+	// Skip git's ownership check if requested
+	if flags.safeDir {
+		cmd.TrustAllDirectories()
+	}
+
+	// Set up the generator, whose client also sends the other requests
+	genConfig := generatorConfig(config)
+	genConfig.Structured = flags.jsonSchema
+	if flags.verbose {
+		genConfig.OnUsage = func(usage cmd.Usage) {
+			fmt.Fprintf(os.Stderr, "Token usage: %d prompt, %d completion\n", usage.PromptTokens, usage.CompletionTokens)
+			if config.PricePerToken > 0 {
+				cost := cmd.EstimateCost(usage.PromptTokens+usage.CompletionTokens, config.PricePerToken)
+				fmt.Fprintf(os.Stderr, "Cost: $%.6f\n", cost)
+			}
+		}
+		genConfig.OnRetry = func(delay time.Duration, err error) {
+			fmt.Fprintf(os.Stderr, "Retrying in %s after: %v\n", delay.Round(time.Millisecond), err)
+		}
+	}
+	generator := newGenerator(genConfig)
+	client, provider := generator.Client(), generator.Provider()
+
+	// List built-in templates if requested
+	if flags.listTemplates {
+		for _, name := range cmd.PromptPresetNames() {
+			fmt.Println(name)
+		}
+		os.Exit(0)
+	}
+
+	// Use a built-in template instead of the configured one if requested
+	if flags.templateName != "" {
+		template, err := cmd.GetPromptPreset(flags.templateName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating config JSON: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		config.PromptTemplate = template
+	}
 
-		// Write to home directory
-		homeDir, err := os.UserHomeDir()
+	// Run in every listed repository and report a summary
+	if flags.batchFile != "" {
+		repos, err := cmd.ReadRepoList(flags.batchFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		configPath := filepath.Join(homeDir, ".ollama-commit.json")
-		if err := os.WriteFile(configPath, configJSON, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing config file: %v\n", err)
+		results := cmd.RunBatch(repos, cmd.WithoutFlag(args, "batch"))
+		failed := 0
+		fmt.Println("Batch summary:")
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+				fmt.Printf("  FAIL %s: %v\n", result.Repo, result.Err)
+			} else {
+				fmt.Printf("  OK   %s\n", result.Repo)
+			}
+		}
+		fmt.Printf("%d of %d repositories succeeded\n", len(results)-failed, len(results))
+		if failed > 0 {
 			os.Exit(1)
 		}
-
-		fmt.Printf("Configuration saved to %s\n", configPath)
 		os.Exit(0)
 	}
 
-	// Get git diff
-	gitDiff, err := cmd.GetGitDiff()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting git diff: %v\n", err)
+	// Refuse models and hosts the configuration doesn't allow
+	if err := cmd.CheckModelAllowed(flags.model, config.AllowedModels); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cmd.CheckHostAllowed(cmd.ProviderURL(provider, flags.ollamaURL), config.AllowedHosts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if gitDiff == "" {
-		fmt.Println("No changes to commit")
+	// Make sure the changes are meant to leave this machine
+	sendsChanges := !flags.offline && !flags.dryRun && !flags.reuseLast && !flags.initConfig && !flags.saveConfig && flags.exportConfig == ""
+	if sendsChanges && !flags.noConfirm {
+		confirmSendingChanges(provider, flags.ollamaURL, config)
+	}
+
+	// Create a configuration interactively if requested
+	if flags.initConfig {
+		initConfigFile(config, client)
 		os.Exit(0)
 	}
 
-	// Generate commit message using Ollama
-	commitMsg, err := cmd.GenerateCommitMessage(gitDiff, *model, *ollamaURL, config.PromptTemplate)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error generating commit message: %v\n", err)
-		os.Exit(1)
+	// Save or export configuration if requested
+	if flags.saveConfig || flags.exportConfig != "" {
+		config.DefaultModel = flags.model
+		config.OllamaAPIURL = flags.ollamaURL
+		config.FindRenames = flags.findRenames
+		config.FindCopies = flags.findCopies
+		config.Plumbing = flags.plumbing
+		config.IgnoreWhitespace = flags.ignoreWhitespace
+		config.ConventionsFile = flags.conventionsFile
+		config.ConnectTimeout = flags.connectTimeout
+		config.Timeout = flags.timeout
+
+		if flags.exportConfig != "" {
+			exportConfigFile(config, flags.exportConfig)
+		}
+
+		if flags.saveConfig {
+			configPath, err := cmd.SaveConfig(config)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Configuration saved to %s\n", configPath)
+		}
+		os.Exit(0)
+	}
+
+	// Describe the branch as a pull request instead of committing
+	if flags.prMode {
+		base := flags.prBase
+		if base == "" {
+			var err error
+			if base, err = cmd.DefaultPRBase(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		branchChanges, err := cmd.GetBranchChanges(base, cmd.DiffOptions{
+			FindRenames:      flags.findRenames,
+			FindCopies:       flags.findCopies,
+			IgnoreWhitespace: flags.ignoreWhitespace,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting branch changes: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if branchChanges == "" {
+			fmt.Printf("No commits on this branch since %s\n", base)
+			os.Exit(exitNoChanges)
+		}
+
+		pr, err := client.GeneratePRDescription(provider, branchChanges, flags.model, config.PRPromptTemplate, optionsFor(flags.model))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating pull request description: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		printMessage("PR title:", pr.Title)
+		printMessage("PR description:", pr.Body)
+		os.Exit(0)
+	}
+
+	// Describe a merge by what the merged branch brings in rather than the combined diff
+	merging := flags.patchFile == "" && flags.templateName == "" && cmd.MergeInProgress()
+	if merging {
+		config.PromptTemplate = config.MergePromptTemplate
+	}
+
+	// Add the project conventions to the prompt
+	if flags.conventionsFile != "" {
+		conventions, truncated, err := cmd.LoadConventions(flags.conventionsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if truncated {
+			fmt.Fprintf(os.Stderr, "Warning: conventions file %s is too large and was truncated\n", flags.conventionsFile)
+		}
+		config.PromptTemplate = cmd.WithConventions(config.PromptTemplate, conventions)
+	}
+
+	// Ask for the footer and body the cleanup expects
+	config.PromptTemplate = withFinishInstructions(config.PromptTemplate, config)
+
+	// Write the message in the requested language
+	if flags.lang != "" && flags.lang != cmd.LanguageAuto {
+		config.PromptTemplate = cmd.WithInstruction(config.PromptTemplate, cmd.LanguageInstruction(flags.lang))
+	}
+
+	// Leaked instructions are looked for in the template so far, not in the
+	// author's hint or what is found out about the changes below, which the
+	// message may rightly repeat
+	instructions := config.PromptTemplate
+
+	// Tell the model what the changes are for
+	if flags.hint != "" {
+		config.PromptTemplate = cmd.WithInstruction(config.PromptTemplate, cmd.IntentHint(flags.hint))
+	}
+
+	// Resolve co-author aliases to full identities
+	var coAuthorIdentities []string
+	for _, alias := range flags.coAuthors {
+		identity, err := cmd.ResolveCoAuthor(alias, config.AuthorMap, config.StrictAuthorMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		coAuthorIdentities = append(coAuthorIdentities, identity)
+	}
+
+	// Line endings used when printing and committing the message
+	lineEnding := cmd.ResolveLineEnding(config.LineEnding)
+
+	diffOpts := cmd.DiffOptions{
+		FindRenames:      flags.findRenames,
+		FindCopies:       flags.findCopies,
+		Base:             flags.baseRef,
+		Plumbing:         flags.plumbing,
+		Paths:            paths,
+		IgnoreWhitespace: flags.ignoreWhitespace,
+	}
+	if !flags.includeGenerated {
+		diffOpts.Exclude = config.GeneratedFiles
+	}
+	var gitDiff, diffHash string
+
+	// Generate commit message for a diff using Ollama
+	generateWith := func(model, diff string) (string, error) {
+		if config.GeneratorCommand != "" {
+			return cmd.RunGenerator(config.GeneratorCommand, diff, model, flags.ollamaURL, config.PromptTemplate)
+		}
+		return generator.Draft(context.Background(), diff, ollamacommit.Options{
+			Model:          model,
+			ModelOptions:   optionsFor(model),
+			PromptTemplate: config.PromptTemplate,
+		})
+	}
+
+	// Files in the changes, read from the patch when describing one
+	changedFiles := func() ([]string, error) {
+		if flags.patchFile != "" {
+			return cmd.PatchFiles(gitDiff), nil
+		}
+		return cmd.GetChangedFiles(diffOpts)
+	}
+
+	// What was last sent to the model, for -show-sent
+	sent := cmd.SentSummary{IgnoreWhitespace: flags.ignoreWhitespace, SubmoduleContext: flags.submoduleContext}
+
+	// Record the generation for the stats and history commands, unless no model was involved
+	var generationTime time.Duration
+	recordOutcome := func(outcome, message string) {
+		if config.DisableStats || flags.offline || sent.Model == "" || generationTime == 0 {
+			return
+		}
+		hash := diffHash
+		if hash == "" {
+			hash = cmd.DiffHash(gitDiff)
+		}
+		record := cmd.GenerationRecord{
+			Time:      time.Now(),
+			Repo:      cmd.CurrentRepo(),
+			Model:     sent.Model,
+			LatencyMS: generationTime.Milliseconds(),
+			DiffBytes: len(gitDiff),
+			DiffHash:  hash,
+			Outcome:   outcome,
+			Message:   message,
+		}
+		if err := cmd.RecordGeneration(record); err != nil && flags.verbose {
+			fmt.Fprintf(os.Stderr, "Warning: could not record the generation: %v\n", err)
+		}
+	}
+
+	// Generate commit message for gitDiff, recovering from diffs too large for the model
+	generate := func() (string, error) {
+		// Build the message from the changed files without calling the model
+		if flags.offline {
+			changes, err := cmd.GetFileChanges(diffOpts)
+			if err != nil {
+				return "", err
+			}
+			return cmd.GenerateOfflineMessage(changes), nil
+		}
+
+		// Describe large changes in two steps: summarize, then write the message from the summary
+		if flags.twoStage && len(gitDiff) >= config.TwoStageThreshold && config.GeneratorCommand == "" {
+			summary, err := client.SummarizeChanges(provider, gitDiff, flags.model, optionsFor(flags.model))
+			if err == nil {
+				if flags.verbose {
+					fmt.Fprintf(os.Stderr, "Summary of the changes:\n%s\n", summary)
+				}
+				sent.Model, sent.Bytes, sent.Files, sent.StatOnly = flags.model, len(gitDiff), cmd.CountDiffFiles(gitDiff), false
+				return generateWith(flags.model, summary)
+			}
+			if !cmd.IsContextLengthError(err) {
+				return "", err
+			}
+			// The diff doesn't fit for the summary either, use the fallbacks below
+		}
+
+		sent.Model, sent.Bytes, sent.Files, sent.StatOnly = flags.model, len(gitDiff), cmd.CountDiffFiles(gitDiff), false
+		commitMsg, err := generateWith(flags.model, gitDiff)
+		if !cmd.IsContextLengthError(err) {
+			return commitMsg, err
+		}
+
+		// Try models with a larger context first
+		for _, fallback := range config.ContextFallbackModels {
+			if cmd.CheckModelAllowed(fallback, config.AllowedModels) != nil {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Diff is too large for the model's context, retrying with %s\n", fallback)
+			sent.Model = fallback
+			commitMsg, err = generateWith(fallback, gitDiff)
+			if !cmd.IsContextLengthError(err) {
+				return commitMsg, err
+			}
+		}
+
+		// Then describe a summary of the changes instead of the full diff
+		if flags.patchFile != "" {
+			return "", err
+		}
+		stat, statErr := cmd.GetGitDiffStat(diffOpts)
+		if statErr != nil {
+			return "", err
+		}
+		fmt.Fprintln(os.Stderr, "Diff is too large for the model's context, sending a summary of the changes instead")
+		sent.Model, sent.Bytes, sent.StatOnly = flags.model, len(stat), true
+		return generateWith(flags.model, stat)
+	}
+
+	// Result of the verification command, recorded as a trailer
+	var verification *cmd.VerificationResult
+
+	// Clean up a message from the model, the same way whether it was generated or refined
+	finish := func(message string) (string, error) {
+		opts := finishOptions(config, changedFiles)
+		opts.PromptTemplate = instructions
+		opts.EnforceImperative = flags.enforceImperative
+		opts.CoAuthors = coAuthorIdentities
+		opts.Verbose = flags.verbose
+		if verification != nil {
+			opts.Trailers = append(opts.Trailers, verification.Trailer())
+		}
+
+		message, notes, err := cmd.FinishMessage(message, opts)
+		for _, note := range notes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		return message, err
+	}
+
+	// Generate the message and clean it up
+	buildMessage := func() (string, error) {
+		commitMsg, err := generate()
+		if err != nil && !(errors.Is(err, cmd.ErrEmptyResponse) && len(config.EscalationModels) > 0) {
+			return "", err
+		}
+
+		// Move up to larger models while the message is empty or too short to be useful
+		if !flags.offline && config.GeneratorCommand == "" && len(strings.TrimSpace(commitMsg)) < config.MinMessageLength {
+			escalated, tried := false, 0
+			for _, larger := range config.EscalationModels {
+				if tried >= config.MaxEscalations {
+					break
+				}
+				if cmd.CheckModelAllowed(larger, config.AllowedModels) != nil {
+					continue
+				}
+				tried++
+				fmt.Fprintf(os.Stderr, "Message is too short, retrying with %s\n", larger)
+				message, genErr := generateWith(larger, gitDiff)
+				if genErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %s failed: %v\n", larger, genErr)
+					continue
+				}
+				commitMsg, sent.Model, escalated, err = message, larger, true, nil
+				if len(strings.TrimSpace(commitMsg)) >= config.MinMessageLength {
+					break
+				}
+			}
+			if escalated {
+				fmt.Fprintf(os.Stderr, "Message generated by %s\n", sent.Model)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Regenerate once if the model did nothing but echo the prompt instructions
+		if cleaned, leaked := cmd.StripPromptLeaks(commitMsg, instructions); leaked && cleaned == "" {
+			fmt.Fprintln(os.Stderr, "Warning: the model only echoed the prompt instructions; regenerating")
+			if commitMsg, err = generate(); err != nil {
+				return "", err
+			}
+		}
+		if commitMsg, err = finish(commitMsg); err != nil {
+			return "", err
+		}
+
+		// Keep the message around so it can be recovered with -reuse-last
+		if flags.patchFile == "" {
+			if err := cmd.SaveLastMessage(commitMsg); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save commit message: %v\n", err)
+			}
+		}
+		return commitMsg, nil
+	}
+
+	// Split the staged changes into one commit per file
+	if flags.separateCommits {
+		if merging {
+			fmt.Fprintln(os.Stderr, "Error: -commit-all-separate can't split a merge; conclude it first")
+			os.Exit(1)
+		}
+		if flags.reuseLast || flags.patchFile != "" || flags.baseRef != "" || len(diffOpts.Paths) > 0 || flags.interactiveRefine {
+			fmt.Fprintln(os.Stderr, "Error: -commit-all-separate can't be used with -reuse-last, -patch-file, -base, -interactive-refine or paths")
+			os.Exit(1)
+		}
+		changes, err := cmd.GetStagedChanges()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if len(changes) == 0 {
+			fmt.Println("No staged changes to commit")
+			os.Exit(exitNoChanges)
+		}
+
+		// Whatever happens, leave the files that weren't committed staged as before
+		snapshot, err := cmd.SnapshotIndex()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		failed := func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, format, args...)
+			if err := cmd.RestoreIndex(snapshot); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v; restore it with 'git read-tree %s'\n", err, snapshot)
+			}
+			os.Exit(1)
+		}
+
+		committed := 0
+		for i, change := range changes {
+			if err := cmd.StageOnly(snapshot, change); err != nil {
+				failed("Error: %v\n", err)
+			}
+			gitDiff, err = cmd.GetGitDiff(diffOpts)
+			if err != nil {
+				failed("Error getting git diff for %s: %v\n", change, err)
+			}
+			start := time.Now()
+			message, err := buildMessage()
+			if err != nil {
+				failed("Error generating commit message for %s: %v\n", change, err)
+			}
+			generationTime = time.Since(start)
+
+			printMessage(fmt.Sprintf("Commit message for %s (%d/%d):", change, i+1, len(changes)), cmd.ApplyLineEnding(message, lineEnding))
+			if !flags.noConfirm && !cmd.ConfirmCommit(message) {
+				recordOutcome(cmd.OutcomeRejected, message)
+				fmt.Printf("Skipped %s, it stays staged\n", change)
+				continue
+			}
+
+			if err := commitMessage(message, lineEnding, flags.transcode, nil); err != nil {
+				failed("Error executing git commit for %s: %v\n", change, err)
+			}
+			recordOutcome(cmd.OutcomeAccepted, message)
+			committed++
+		}
+
+		if err := cmd.RestoreIndex(snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v; restore it with 'git read-tree %s'\n", err, snapshot)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %d of %d commits\n", committed, len(changes))
+		os.Exit(0)
+	}
+
+	var commitMsg string
+	if flags.reuseLast {
+		// Reuse the message saved by a previous run instead of calling the model
+		lastMsg, err := cmd.LoadLastMessage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading last commit message: %v\n", err)
+			os.Exit(1)
+		}
+		commitMsg = cmd.AddCoAuthors(lastMsg, coAuthorIdentities)
+	} else {
+		var err error
+		if flags.patchFile != "" {
+			// Describe a patch that hasn't been applied, so there is nothing to commit
+			if flags.autoCommit || flags.offline || flags.nameStatus {
+				fmt.Fprintln(os.Stderr, "Error: -patch-file can't be used with -a, -offline or -name-status")
+				os.Exit(1)
+			}
+			gitDiff, err = cmd.ReadPatchFile(flags.patchFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			// Get git diff
+			gitDiff, err = cmd.GetGitDiff(diffOpts)
+			if errors.Is(err, cmd.ErrNoChanges) {
+				fmt.Println("No changes to commit")
+				os.Exit(exitNoChanges)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting git diff: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+		}
+
+		diffHash = cmd.DiffHash(gitDiff)
+
+		// Describe what submodule updates bring in
+		if flags.submoduleContext {
+			gitDiff += cmd.SubmoduleContext(gitDiff)
+		}
+
+		// Spell out each file's change type
+		if flags.nameStatus {
+			gitDiff = cmd.NameStatusContext(diffOpts) + gitDiff
+		}
+		if merging {
+			gitDiff = cmd.MergeContext() + gitDiff
+		}
+
+		// Don't spend a generation on changes the filters reduced to nothing
+		if !cmd.HasMeaningfulChanges(gitDiff) && !flags.force {
+			fmt.Println("No meaningful changes to commit after filters")
+			os.Exit(exitNoChanges)
+		}
+
+		// Catch build artifacts or datasets that were added by accident
+		if config.SizeWarnBytes > 0 && flags.patchFile == "" {
+			if large, err := cmd.FindLargeFiles(diffOpts, config.SizeWarnBytes); err == nil && len(large) > 0 {
+				for _, file := range large {
+					fmt.Fprintf(os.Stderr, "Warning: %s is %s, larger than sizeWarnBytes (%s)\n", file.Path, cmd.FormatSize(file.Size), cmd.FormatSize(config.SizeWarnBytes))
+				}
+				if config.RefuseLargeFiles && !flags.force {
+					fmt.Fprintln(os.Stderr, "Error: large files are staged; unstage them or use -force")
+					os.Exit(1)
+				}
+			}
+		}
+
+		// Match the language of the comments in the changes
+		if flags.lang == cmd.LanguageAuto {
+			detected := cmd.DetectLanguage(gitDiff)
+			if flags.verbose {
+				fmt.Fprintf(os.Stderr, "Detected language: %s\n", detected)
+			}
+			config.PromptTemplate = cmd.WithInstruction(config.PromptTemplate, cmd.LanguageInstruction(detected))
+		}
+
+		// Surface new TODOs before they are forgotten
+		if flags.warnTodo || flags.strictTodo {
+			if todos := cmd.FindTodos(gitDiff, config.TodoMarkers); len(todos) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: the changes add %d TODO marker(s):\n", len(todos))
+				for _, todo := range todos {
+					fmt.Fprintf(os.Stderr, "  %s:%d: %s\n", todo.Path, todo.Line, todo.Text)
+				}
+				if flags.strictTodo {
+					fmt.Fprintln(os.Stderr, "Error: resolve the markers or commit without -strict-todo")
+					os.Exit(1)
+				}
+			}
+		}
+
+		// Run the tests or linters so the message can reflect them
+		if config.VerificationCommand != "" && flags.patchFile == "" && !flags.dryRun {
+			result := cmd.RunVerification(config.VerificationCommand, time.Duration(config.VerificationTimeout)*time.Second)
+			if flags.verbose {
+				fmt.Fprintf(os.Stderr, "Verification: %s %s\n", result.Status(), result.Summary)
+			}
+			config.PromptTemplate = cmd.WithInstruction(config.PromptTemplate, result.Instruction())
+			verification = &result
+		}
+
+		// Tell the model about the kind of files that changed
+		if len(config.FileTypeHints) > 0 {
+			if files, err := changedFiles(); err == nil {
+				config.PromptTemplate = cmd.WithInstruction(config.PromptTemplate, cmd.FileTypeHint(config.FileTypeHints, files))
+			}
+		}
+
+		// Estimate the size and cost of the prompt before sending it
+		if flags.verbose || flags.dryRun {
+			tokens := cmd.EstimateTokens(fmt.Sprintf(config.PromptTemplate, gitDiff))
+			fmt.Fprintf(os.Stderr, "Estimated prompt tokens: %d\n", tokens)
+			if config.PricePerToken > 0 {
+				fmt.Fprintf(os.Stderr, "Estimated prompt cost: $%.6f\n", cmd.EstimateCost(tokens, config.PricePerToken))
+			}
+		}
+		if flags.dryRun {
+			os.Exit(0)
+		}
+
+		// Print a review of the changes instead of a commit message
+		if flags.review && flags.offline {
+			fmt.Fprintln(os.Stderr, "Error: -review needs the model and can't be used with -offline")
+			os.Exit(1)
+		}
+		if flags.review {
+			critique, err := client.GenerateReview(provider, gitDiff, flags.model, config.ReviewPromptTemplate, optionsFor(flags.model))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating review: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			fmt.Println(critique)
+			os.Exit(0)
+		}
+
+		// Print a walkthrough of the changes instead of a commit message
+		if flags.explain && flags.offline {
+			fmt.Fprintln(os.Stderr, "Error: -explain needs the model and can't be used with -offline")
+			os.Exit(1)
+		}
+		if flags.explain {
+			explanation, err := client.GenerateExplanation(provider, gitDiff, flags.model, config.ExplainPromptTemplate, optionsFor(flags.model))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating explanation: %v\n", err)
+				os.Exit(exitCode(err))
+			}
+			fmt.Println(strings.TrimSpace(explanation))
+			os.Exit(0)
+		}
+
+		// Compare two models on the same changes instead of committing
+		if flags.compare != "" {
+			models := strings.Split(flags.compare, ",")
+			if len(models) != 2 || strings.TrimSpace(models[0]) == "" || strings.TrimSpace(models[1]) == "" {
+				fmt.Fprintln(os.Stderr, "Error: -compare needs two comma-separated models, e.g. -compare llama3,qwen2.5-coder")
+				os.Exit(1)
+			}
+
+			var headers, messages [2]string
+			for i, name := range models {
+				name = strings.TrimSpace(name)
+				if err := cmd.CheckModelAllowed(name, config.AllowedModels); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+
+				start := time.Now()
+				message, err := generateWith(name, gitDiff)
+				elapsed := time.Since(start).Round(time.Millisecond)
+				if err != nil {
+					message = fmt.Sprintf("Error: %v", err)
+				}
+				headers[i] = fmt.Sprintf("%s (%s)", name, elapsed)
+				messages[i] = message
+			}
+			fmt.Print(cmd.SideBySide(headers[0], messages[0], headers[1], messages[1]))
+			os.Exit(0)
+		}
+
+		start := time.Now()
+		commitMsg, err = buildMessage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating commit message: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		generationTime = time.Since(start)
+
+		// Show what the model actually got
+		if flags.showSent && !flags.offline {
+			fmt.Fprintln(os.Stderr, sent)
+		}
 	}
 
 	// Print the generated commit message
-	fmt.Println("Generated commit message:")
-	fmt.Println("------------------------")
-	fmt.Println(commitMsg)
-	fmt.Println("------------------------")
+	if flags.previewLog {
+		fmt.Print(cmd.LogPreview(commitMsg))
+	} else if !flags.quiet {
+		printMessage("Generated commit message:", cmd.ApplyLineEnding(commitMsg, lineEnding))
+	}
+
+	// How much the user steered the message, recorded by -add-note
+	refinements, subjectRegenerated := 0, false
+
+	// Let the user refine the message with the model until they accept it
+	if flags.interactiveRefine && !flags.reuseLast && !flags.offline {
+		// Conversation state from the last refinement, so follow-ups don't resend the diff
+		var conversation []int
+		for {
+			instruction, err := cmd.ReadLine("Refine the message (e.g. \"make it shorter\"), or press Enter to accept: ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+				os.Exit(1)
+			}
+			if instruction == "" {
+				break
+			}
+
+			refined, next, err := client.RefineCommitMessage(provider, gitDiff, commitMsg, instruction, flags.model, optionsFor(flags.model), conversation)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error refining commit message: %v\n", err)
+				continue
+			}
+			if refined, err = finish(refined); err != nil {
+				fmt.Fprintf(os.Stderr, "Error refining commit message: %v\n", err)
+				continue
+			}
+			if strings.TrimSpace(refined) == "" {
+				fmt.Fprintln(os.Stderr, "Error refining commit message: the model returned nothing usable")
+				continue
+			}
+			conversation = next
+			refinements++
+			commitMsg = refined
+
+			printMessage("Refined commit message:", cmd.ApplyLineEnding(commitMsg, lineEnding))
+		}
+
+		if flags.patchFile == "" {
+			if err := cmd.SaveLastMessage(commitMsg); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save commit message: %v\n", err)
+			}
+		}
+	}
+
+	// Write the message to the output file if requested
+	if flags.outputPath != "" {
+		if err := cmd.WriteFileAtomic(flags.outputPath, []byte(cmd.ApplyLineEnding(commitMsg+"\n", lineEnding)), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// If auto-commit flag is set
-	if *autoCommit {
+	// If auto-commit flag is set (reusing the last message always commits)
+	if flags.autoCommit || flags.reuseLast {
 		// Skip confirmation if -y flag is provided
-		if !*noConfirm {
-			confirmed := cmd.ConfirmCommit(commitMsg)
-			if !confirmed {
-				fmt.Println("Commit aborted.")
+		for !flags.noConfirm {
+			action := cmd.AskCommitAction(!flags.reuseLast && !flags.offline)
+			if action == "y" {
+				break
+			}
+			if action == "n" {
+				recordOutcome(cmd.OutcomeRejected, commitMsg)
+				fmt.Println("Commit aborted. Use -reuse-last to commit this message later.")
 				os.Exit(0)
 			}
+
+			// Keep the body and only ask the model for a better subject
+			_, body := cmd.SplitMessage(commitMsg, config.SubjectSeparator)
+			subject, err := client.RegenerateSubject(provider, gitDiff, body, flags.model, optionsFor(flags.model))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error regenerating subject: %v\n", err)
+				continue
+			}
+			if subject, err = finish(cmd.JoinMessage(subject, body)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error regenerating subject: %v\n", err)
+				continue
+			}
+			commitMsg = subject
+			subjectRegenerated = true
+			if err := cmd.SaveLastMessage(commitMsg); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save commit message: %v\n", err)
+			}
+			printMessage("Generated commit message:", cmd.ApplyLineEnding(commitMsg, lineEnding))
 		}
 
-		err = cmd.ExecuteGitCommit(commitMsg)
-		if err != nil {
+		// Make sure the changes weren't modified while the message was generated
+		if !flags.reuseLast && !flags.force {
+			currentDiff, err := cmd.GetGitDiff(diffOpts)
+			if errors.Is(err, cmd.ErrNoChanges) {
+				err = nil
+			}
+			if err == nil && cmd.DiffHash(currentDiff) != diffHash {
+				fmt.Fprintln(os.Stderr, "Warning: the changes were modified after the commit message was generated")
+				if flags.noConfirm {
+					// Describe what will actually be committed
+					gitDiff = currentDiff
+					if flags.submoduleContext {
+						gitDiff += cmd.SubmoduleContext(currentDiff)
+					}
+					if flags.nameStatus {
+						gitDiff = cmd.NameStatusContext(diffOpts) + gitDiff
+					}
+					if merging {
+						gitDiff = cmd.MergeContext() + gitDiff
+					}
+					commitMsg, err = buildMessage()
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error generating commit message: %v\n", err)
+						os.Exit(exitCode(err))
+					}
+					printMessage("Regenerated commit message:", cmd.ApplyLineEnding(commitMsg, lineEnding))
+				} else if !cmd.ConfirmCommit(commitMsg) {
+					recordOutcome(cmd.OutcomeRejected, commitMsg)
+					fmt.Println("Commit aborted. Use -reuse-last to commit this message later.")
+					os.Exit(0)
+				}
+			}
+		}
+
+		if err := commitMessage(commitMsg, lineEnding, flags.transcode, diffOpts.Paths); err != nil {
 			fmt.Fprintf(os.Stderr, "Error executing git commit: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Println("Changes committed successfully!")
+		recordOutcome(cmd.OutcomeAccepted, commitMsg)
+
+		// Record how the message was generated without touching the message itself
+		if flags.addNote {
+			note := cmd.NewGenerationNote(sent.Model, config.PromptTemplate)
+			note.Offline = flags.offline
+			note.ReusedLast = flags.reuseLast
+			note.Refinements = refinements
+			note.SubjectRegenerated = subjectRegenerated
+			if err := cmd.AddNote(note); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		// Show what actually got committed if a hook changed the message
+		if flags.verifyCommit {
+			committed, err := cmd.CommittedMessage()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			} else if !cmd.SameMessage(committed, commitMsg) {
+				fmt.Fprintln(os.Stderr, "Warning: the committed message differs from the generated one, probably changed by a commit-msg hook")
+				printMessage("Committed message:", committed)
+			} else if !flags.quiet {
+				fmt.Println("Verified the committed message")
+			}
+		}
 	} else {
-		fmt.Println("Use -a flag to automatically commit with this message")
+		recordOutcome(cmd.OutcomeShown, commitMsg)
+		if !flags.quiet && flags.patchFile == "" {
+			fmt.Println("Use -a flag to automatically commit with this message")
+		}
+	}
+}
+
+// confirmSendingChanges asks before the changes are sent to an API that isn't
+// on this machine, unless the config acknowledges it. provider was created
+// for apiURL and tells where the requests actually go.
+func confirmSendingChanges(provider cmd.Provider, apiURL string, config cmd.Config) {
+	apiURL = cmd.ProviderURL(provider, apiURL)
+	if cmd.IsLocalURL(apiURL) || config.AcknowledgedRemote {
+		return
+	}
+	if !cmd.StdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "Error: %s is not on this machine; set acknowledgedRemote in the config or use -y to send your changes there\n", apiURL)
+		os.Exit(1)
+	}
+	answer, err := cmd.ReadLine(fmt.Sprintf("You're about to send your changes to %s. Continue? (y/n): ", apiURL))
+	if err != nil || (strings.ToLower(answer) != "y" && strings.ToLower(answer) != "yes") {
+		fmt.Println("Aborted.")
+		os.Exit(0)
+	}
+}
+
+// withFinishInstructions asks the model for the breaking change footer and
+// the body length the cleanup of its message expects
+func withFinishInstructions(template string, config cmd.Config) string {
+	if config.BreakingChangeFooter {
+		template = cmd.WithBreakingChangeInstructions(template)
+	}
+	if config.MaxBodyBullets > 0 {
+		template = cmd.WithInstruction(template, cmd.BulletLimitInstruction(config.MaxBodyBullets))
+	}
+	return template
+}
+
+// finishOptions returns the configured cleanup of messages from the model,
+// the same for every command that commits with them. changedFiles lists the
+// files in the changes, for the scope.
+func finishOptions(config cmd.Config, changedFiles func() ([]string, error)) cmd.FinishOptions {
+	opts := cmd.FinishOptions{
+		PromptTemplate:       config.PromptTemplate,
+		SubjectSeparator:     config.SubjectSeparator,
+		SubjectCase:          config.SubjectCase,
+		MaxBodyBullets:       config.MaxBodyBullets,
+		BreakingChangeFooter: config.BreakingChangeFooter,
+		MaxMessageBytes:      config.MaxMessageBytes,
+		LineEnding:           cmd.ResolveLineEnding(config.LineEnding),
+	}
+	// Add a scope derived from the owners of the changed files
+	if config.ScopeFromCodeowners {
+		if files, err := changedFiles(); err == nil {
+			opts.Scope = cmd.InferScope(files)
+		}
+	}
+	if config.RefsFooter {
+		opts.Ticket = cmd.TicketFromBranch(config.TicketPattern)
+	}
+	return opts
+}
+
+// commitMessage commits with a finished message in the given line endings,
+// converted to the repository's commit encoding if transcode is set
+func commitMessage(message, lineEnding string, transcode bool, paths []string) error {
+	opts := cmd.CommitOptions{Paths: paths}
+	if transcode {
+		opts.Encoding = cmd.CommitEncoding()
 	}
+	return cmd.ExecuteGitCommit(cmd.ApplyLineEnding(message, lineEnding), opts)
 }
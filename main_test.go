@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when a test starts the test
+// binary as ollama-commit
+func TestMain(m *testing.M) {
+	if os.Getenv("OLLAMA_COMMIT_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs ollama-commit with args in dir and returns its exit code
+func runMain(t *testing.T, dir string, args ...string) int {
+	t.Helper()
+	command := exec.Command(os.Args[0], args...)
+	command.Dir = dir
+	command.Env = append(os.Environ(), "OLLAMA_COMMIT_RUN_MAIN=1", "HOME="+t.TempDir())
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+// gitRepo returns a repository with one commit of file.txt
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("one two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", "file.txt")
+	git(t, dir, "commit", "-q", "-m", "Initial commit")
+	return dir
+}
+
+// git runs git in dir with a fixed identity
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	command := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	command.Dir = dir
+	if out, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestNoChangesExitCode(t *testing.T) {
+	t.Run("nothing staged", func(t *testing.T) {
+		if code := runMain(t, gitRepo(t)); code != exitNoChanges {
+			t.Errorf("exit code = %d, want %d", code, exitNoChanges)
+		}
+	})
+	t.Run("only file headers", func(t *testing.T) {
+		dir := gitRepo(t)
+		patch := filepath.Join(dir, "headers.patch")
+		if err := os.WriteFile(patch, []byte("diff --git a/file.txt b/file.txt\nindex 3e75765..ed6e1f1 100644\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if code := runMain(t, dir, "-patch-file", patch); code != exitNoChanges {
+			t.Errorf("exit code = %d, want %d", code, exitNoChanges)
+		}
+	})
+	t.Run("nothing staged for separate commits", func(t *testing.T) {
+		if code := runMain(t, gitRepo(t), "-commit-all-separate"); code != exitNoChanges {
+			t.Errorf("exit code = %d, want %d", code, exitNoChanges)
+		}
+	})
+	t.Run("no commits on the branch", func(t *testing.T) {
+		if code := runMain(t, gitRepo(t), "-pr", "-pr-base", "HEAD"); code != exitNoChanges {
+			t.Errorf("exit code = %d, want %d", code, exitNoChanges)
+		}
+	})
+}
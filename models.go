@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
+)
+
+// modelsGenerator returns the generator whose client and provider list the
+// models of the configured server
+func modelsGenerator(config cmd.Config) *ollamacommit.Generator {
+	generator := newGenerator(generatorConfig(config))
+	if err := cmd.CheckHostAllowed(cmd.ProviderURL(generator.Provider(), config.OllamaAPIURL), config.AllowedHosts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return generator
+}
+
+func runModels(args []string) {
+	parseArgs(findCommand(commands, "models"), "models", args, 0)
+	config := loadConfig()
+	provider := modelsGenerator(config).Provider()
+
+	models, err := cmd.ListModels(provider)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing models: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	lmStudio := provider.Name() == "lmstudio"
+	if len(models) == 0 {
+		if lmStudio {
+			fmt.Println("No models loaded; load one in LM Studio or with 'lms load <model>'")
+			return
+		}
+		fmt.Println("No models installed; pull one with 'ollama pull <model>'")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSIZE\tPARAMETERS\tFAMILY")
+	for _, model := range models {
+		marker := " "
+		if cmd.HasModel([]cmd.ModelInfo{model}, config.DefaultModel) {
+			marker = "*"
+		}
+		size := "-"
+		if model.Size > 0 {
+			size = cmd.FormatSize(model.Size)
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\n", marker, model.Name, size, model.Details.ParameterSize, model.Details.Family)
+	}
+	w.Flush()
+
+	if !cmd.HasModel(models, config.DefaultModel) {
+		if lmStudio {
+			fmt.Printf("\nThe configured model %s is not loaded\n", config.DefaultModel)
+			return
+		}
+		fmt.Printf("\nThe configured model %s is not installed\n", config.DefaultModel)
+	}
+}
+
+func runModelsUse(args []string) {
+	fs := parseArgs(findCommand(findCommand(commands, "models").subcommands, "use"), "models use", args, 1)
+	name := fs.Arg(0)
+	config := loadConfig()
+	provider := modelsGenerator(config).Provider()
+
+	// Only check that the model exists where the provider can tell
+	var models []cmd.ModelInfo
+	if cmd.CanListModels(provider) {
+		var err error
+		if models, err = cmd.ListModels(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing models: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+	if cmd.CanListModels(provider) && !cmd.HasModel(models, name) {
+		if provider.Name() == "lmstudio" {
+			fmt.Fprintf(os.Stderr, "Error: %s is not loaded; run 'lms load %s' first\n", name, name)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s is not installed; run 'ollama pull %s' first\n", name, name)
+		}
+		os.Exit(1)
+	}
+
+	path, err := cmd.EditableConfigPath(cmd.LoadConfig(), "defaultModel")
+	if err == nil {
+		err = cmd.SetConfigValue(path, "defaultModel", name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Default model set to %s in %s\n", name, path)
+}
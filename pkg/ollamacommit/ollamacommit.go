@@ -0,0 +1,271 @@
+// Package ollamacommit generates git commit messages with a language model.
+//
+// It runs the pipeline of the ollama-commit command, from collecting the
+// changes to cleaning up the model's answer, so other tools can embed commit
+// message generation:
+//
+//	gen, err := ollamacommit.New(ollamacommit.Config{Model: "llama3"})
+//	if err != nil {
+//		return err
+//	}
+//	message, err := gen.Generate(ctx, ollamacommit.Options{})
+//	if errors.Is(err, ollamacommit.ErrNoChanges) {
+//		// nothing to describe
+//	}
+//
+// Each Generator has its own HTTP client, retries and limits, so generators
+// with different settings can be used side by side. Git commands run in the
+// working directory of the process, unless Options.Dir names the repository.
+package ollamacommit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+// ModelOptions holds the model parameters sent with a request, such as the
+// temperature. Unset fields keep the server's defaults.
+type ModelOptions = cmd.Options
+
+// Usage is the token usage the API reported for a request
+type Usage = cmd.Usage
+
+// Errors returned by Generate, for use with errors.Is
+var (
+	ErrNoChanges      = cmd.ErrNoChanges      // There are no changes to describe
+	ErrNotARepo       = cmd.ErrNotARepo       // The working directory is not in a git repository
+	ErrModelNotFound  = cmd.ErrModelNotFound  // The model is not installed on the server
+	ErrAPIUnreachable = cmd.ErrAPIUnreachable // The API could not be reached
+	ErrEmptyResponse  = cmd.ErrEmptyResponse  // The model returned no text
+)
+
+// Config configures a Generator. The zero value of each field keeps the
+// default behavior; Model must be set here or for each generation.
+type Config struct {
+	// The model and how to ask it
+	APIURL          string            // API endpoint, a local Ollama server if empty
+	Provider        string            // Backend such as "ollama" or "openai", detected from APIURL if empty
+	Model           string            // Model to use
+	PromptTemplate  string            // Prompt with a %s where the diff goes, the built-in prompt if empty
+	ModelOptions    *ModelOptions     // Model parameters, the server's defaults if nil
+	Structured      bool              // Ask for the message as JSON and assemble it from the fields
+	JSONSchema      json.RawMessage   // Schema of the structured answer, plain JSON mode if empty
+	APIKeys         map[string]string // API keys by provider, used when no environment variable gives one
+	UntrustedAPIURL bool              // APIURL comes from a source that mustn't get API keys, such as a cloned repository; keys then only go to the providers' own hosts
+	AzureDeployment string            // Deployment of the azure provider, the model name if empty
+	AzureAPIVersion string            // api-version of the azure provider, a recent one if empty
+	BedrockRegion   string            // AWS region of the bedrock provider, AWS_REGION if empty
+	ResponseFields  []string          // Field names searched for the generated text, in order of preference
+
+	// Requests
+	AllowedHosts            []string      // Hosts requests may be sent to, any host if empty
+	ConnectTimeout          time.Duration // Longest wait for a connection, 0 means no limit
+	Timeout                 time.Duration // Longest wait for a whole request, 0 means no limit
+	StreamIdleTimeout       time.Duration // Stream the answer and cancel if no token arrives for this long, 0 disables streaming
+	StreamFirstTokenTimeout time.Duration // Longest wait for the first streamed token, 0 means no limit
+	Retries                 int           // Times a request failing with a network or server error is retried
+	RetryOnRateLimit        bool          // Retry requests rejected with 429 after the delay the server asks for
+	RateLimit               float64       // Most requests per second, 0 means unlimited
+	MaxConcurrency          int           // Most requests in flight at once, 0 means unlimited
+
+	// Cleanup of the generated message
+	SubjectSeparator     string // How the subject is told from the body: blank-line (the default) or newline
+	EnforceImperative    bool   // Rewrite past tense subjects in imperative mood
+	SubjectCase          string // Case of the subject's first letter: preserve (the default), sentence or lower
+	MaxBodyBullets       int    // Most bullet points in the body, 0 means unlimited
+	BreakingChangeFooter bool   // Ask for and normalize a BREAKING CHANGE footer
+	MaxMessageBytes      int    // Most bytes in the message, 0 means unlimited
+	LineEnding           string // Line ending the message will be committed with, lf or crlf, for measuring its size
+
+	OnRetry func(delay time.Duration, err error) // If set, called before waiting to retry a failed request
+	OnUsage func(Usage)                          // If set, called with the token usage of every response that reports it
+	OnStall func(idle time.Duration)             // If set, called when a stalled stream is cut short and its partial output used
+}
+
+// Generator generates commit messages. Create one with New.
+type Generator struct {
+	config   Config
+	client   *cmd.Client
+	provider cmd.Provider
+}
+
+// New returns a Generator with the given configuration, or an error if the
+// configuration is invalid
+func New(config Config) (*Generator, error) {
+	if config.APIURL == "" {
+		config.APIURL = cmd.DefaultAPIURL
+	}
+	if config.PromptTemplate == "" {
+		config.PromptTemplate = cmd.DefaultConfig().PromptTemplate
+	}
+	if config.ModelOptions != nil {
+		if err := config.ModelOptions.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	client := cmd.NewClient()
+	client.SetUntrustedURLs(config.UntrustedAPIURL)
+	for provider, key := range config.APIKeys {
+		client.SetAPIKey(provider, key)
+	}
+	client.SetAzureDeployment(config.AzureDeployment, config.AzureAPIVersion)
+	client.SetBedrockRegion(config.BedrockRegion)
+	client.SetResponseFields(config.ResponseFields)
+	client.SetAllowedHosts(config.AllowedHosts)
+	if config.ConnectTimeout > 0 || config.Timeout > 0 {
+		client.SetTimeouts(config.ConnectTimeout, config.Timeout)
+	}
+	client.SetStreaming(config.StreamFirstTokenTimeout, config.StreamIdleTimeout)
+	client.SetRetries(config.Retries)
+	client.SetRetryOnRateLimit(config.RetryOnRateLimit)
+	client.SetRateLimit(config.RateLimit)
+	client.SetMaxConcurrency(config.MaxConcurrency)
+	client.OnRetry = config.OnRetry
+	client.OnUsage = config.OnUsage
+	client.OnStall = config.OnStall
+
+	provider, err := cmd.NewProvider(client, config.Provider, config.APIURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{config: config, client: client, provider: provider}, nil
+}
+
+// Client returns the API client the generator sends its requests with, for
+// making other requests under the same settings and limits
+func (g *Generator) Client() *cmd.Client {
+	return g.client
+}
+
+// Provider returns the backend the generator sends its requests to, for
+// making other requests with Client
+func (g *Generator) Provider() cmd.Provider {
+	return g.provider
+}
+
+// Options controls a single generation
+type Options struct {
+	Diff             string   // Changes to describe; if empty, the staged changes are collected, or the unstaged ones if nothing is staged
+	Paths            []string // Limit the collected changes to these pathspecs
+	Base             string   // Ref to diff against instead of HEAD
+	IgnoreWhitespace bool     // Leave whitespace-only changes out of the collected diff
+	IncludeGenerated bool     // Keep lock and generated files in the collected diff
+	Hint             string   // One-line description of the intent of the changes, given to the model
+	Dir              string   // Repository to collect the changes from, the working directory if empty
+
+	Model          string        // Model to use instead of the configured one
+	ModelOptions   *ModelOptions // Model parameters of Model, used instead of the configured ones when Model is set
+	PromptTemplate string        // Prompt to use instead of the configured one
+	CoAuthors      []string      // Identities added as Co-authored-by trailers
+	Trailers       []string      // Extra trailers, such as a test result
+}
+
+// Generate returns a commit message for the changes, cleaned up as
+// configured. It returns ErrNoChanges if there is nothing to describe, and
+// gives up when ctx is done.
+func (g *Generator) Generate(ctx context.Context, opts Options) (string, error) {
+	diff := opts.Diff
+	if diff == "" {
+		diffOpts := cmd.DiffOptions{
+			Base:             opts.Base,
+			Paths:            opts.Paths,
+			IgnoreWhitespace: opts.IgnoreWhitespace,
+			Dir:              opts.Dir,
+		}
+		if !opts.IncludeGenerated {
+			diffOpts.Exclude = cmd.DefaultGeneratedFiles
+		}
+
+		var err error
+		if diff, err = cmd.GetGitDiff(diffOpts); err != nil {
+			return "", err
+		}
+	}
+
+	template, instructions := g.promptTemplate(opts), g.instructions(opts)
+	message, err := g.draft(ctx, diff, template, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Regenerate once if the model did nothing but echo the prompt instructions
+	if cleaned, leaked := cmd.StripPromptLeaks(message, instructions); leaked && cleaned == "" {
+		if message, err = g.draft(ctx, diff, template, opts); err != nil {
+			return "", err
+		}
+	}
+
+	message, _, err = cmd.FinishMessage(message, cmd.FinishOptions{
+		PromptTemplate:       instructions,
+		SubjectSeparator:     g.config.SubjectSeparator,
+		EnforceImperative:    g.config.EnforceImperative,
+		SubjectCase:          g.config.SubjectCase,
+		MaxBodyBullets:       g.config.MaxBodyBullets,
+		BreakingChangeFooter: g.config.BreakingChangeFooter,
+		Trailers:             opts.Trailers,
+		CoAuthors:            opts.CoAuthors,
+		MaxMessageBytes:      g.config.MaxMessageBytes,
+		LineEnding:           g.config.LineEnding,
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(message) == "" {
+		return "", ErrEmptyResponse
+	}
+	return message, nil
+}
+
+// Draft returns the model's answer for diff before any cleanup, for callers
+// that clean up the message themselves
+func (g *Generator) Draft(ctx context.Context, diff string, opts Options) (string, error) {
+	return g.draft(ctx, diff, g.promptTemplate(opts), opts)
+}
+
+// promptTemplate returns the prompt for a generation: the instructions plus
+// the author's hint
+func (g *Generator) promptTemplate(opts Options) string {
+	template := g.instructions(opts)
+	if opts.Hint != "" {
+		template = cmd.WithInstruction(template, cmd.IntentHint(opts.Hint))
+	}
+	return template
+}
+
+// instructions returns the prompt template with the instructions the
+// configuration and options call for. Leaked instructions are looked for in
+// it, so a message repeating the hint is kept.
+func (g *Generator) instructions(opts Options) string {
+	template := g.config.PromptTemplate
+	if opts.PromptTemplate != "" {
+		template = opts.PromptTemplate
+	}
+	if g.config.BreakingChangeFooter {
+		template = cmd.WithBreakingChangeInstructions(template)
+	}
+	if g.config.MaxBodyBullets > 0 {
+		template = cmd.WithInstruction(template, cmd.BulletLimitInstruction(g.config.MaxBodyBullets))
+	}
+	return template
+}
+
+// draft sends the prompt for diff to the model
+func (g *Generator) draft(ctx context.Context, diff, template string, opts Options) (string, error) {
+	model, options := g.config.Model, g.config.ModelOptions
+	if opts.Model != "" {
+		model, options = opts.Model, opts.ModelOptions
+	}
+	if model == "" {
+		return "", errors.New("no model configured")
+	}
+	if g.config.Structured {
+		return g.client.GenerateStructuredCommitMessageContext(ctx, g.provider, diff, model, template, g.config.JSONSchema, options)
+	}
+	return g.client.GenerateCommitMessageContext(ctx, g.provider, diff, model, template, options)
+}
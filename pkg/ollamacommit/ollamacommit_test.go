@@ -0,0 +1,95 @@
+package ollamacommit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyServer fails the first request with a server error and answers the
+// later ones with message
+func flakyServer(t *testing.T, message string) *httptest.Server {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"response": message})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGeneratorsAreIndependent(t *testing.T) {
+	retrying, err := New(Config{APIURL: flakyServer(t, "Add login").URL, Model: "m", Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := New(Config{APIURL: flakyServer(t, "Add login").URL, Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted, err := New(Config{APIURL: flakyServer(t, "Add login").URL, Model: "m", AllowedHosts: []string{"example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating the later generators must not change the settings of the earlier ones
+	if message, err := retrying.Generate(context.Background(), Options{Diff: "diff"}); err != nil || message != "Add login" {
+		t.Errorf("retrying generator = %q, %v; want the message after one retry", message, err)
+	}
+	if _, err := failing.Generate(context.Background(), Options{Diff: "diff"}); err == nil {
+		t.Error("generator without retries succeeded, want the server error")
+	}
+	if _, err := restricted.Generate(context.Background(), Options{Diff: "diff"}); err == nil || !strings.Contains(err.Error(), "allowedHosts") {
+		t.Errorf("generator limited to example.com error = %v, want the host to be refused", err)
+	}
+}
+
+func TestGenerateCleansUpMessage(t *testing.T) {
+	generator, err := New(Config{
+		APIURL:            flakyServer(t, "Added login\n\nUsers can sign in.").URL,
+		Model:             "m",
+		Retries:           1,
+		EnforceImperative: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := generator.Generate(context.Background(), Options{Diff: "diff", Trailers: []string{"Tested: go test (passed)"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Add login\n\nUsers can sign in.\n\nTested: go test (passed)"
+	if message != want {
+		t.Errorf("Generate() = %q, want %q", message, want)
+	}
+}
+
+func TestNewSelectsProvider(t *testing.T) {
+	generator, err := New(Config{APIURL: "http://localhost:8000/v1/chat/completions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := generator.Provider().Name(); name != "openai" {
+		t.Errorf("provider detected from the URL = %q, want openai", name)
+	}
+
+	if generator, err = New(Config{Provider: "lmstudio"}); err != nil {
+		t.Fatal(err)
+	}
+	if name := generator.Provider().Name(); name != "lmstudio" {
+		t.Errorf("configured provider = %q, want lmstudio", name)
+	}
+
+	if _, err := New(Config{Provider: "nope"}); err == nil {
+		t.Error("New() with an unknown provider succeeded")
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+	"github.com/mrandiw/ollama-commit/pkg/ollamacommit"
+)
+
+// maxServeRequestBytes limits the size of a request to the HTTP API
+const maxServeRequestBytes = 32 << 20
+
+// serveTokenEnv names the environment variable setting the token clients of
+// the HTTP API must send, instead of a random one for each start
+const serveTokenEnv = "OLLAMA_COMMIT_SERVE_TOKEN"
+
+// generateRequest is the body of POST /generate. Either Diff or Repo gives the changes.
+type generateRequest struct {
+	Diff             string   `json:"diff"`
+	Repo             string   `json:"repo"`
+	Paths            []string `json:"paths"`
+	Base             string   `json:"base"`
+	IgnoreWhitespace bool     `json:"ignoreWhitespace"`
+	IncludeGenerated bool     `json:"includeGenerated"`
+	Hint             string   `json:"hint"`
+	Model            string   `json:"model"`
+}
+
+// generateResponse is the body of a successful POST /generate
+type generateResponse struct {
+	Message string `json:"message"`
+	Model   string `json:"model"`
+}
+
+// writeJSON writes value as the JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// errorStatus maps a generation error to an HTTP status
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, cmd.ErrNoChanges):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, cmd.ErrNotARepo):
+		return http.StatusBadRequest
+	case errors.Is(err, cmd.ErrModelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, cmd.ErrAPIUnreachable):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// checkHost rejects requests whose Host header names neither the listen
+// address nor localhost, so a web page can't reach the API by pointing its own
+// domain at a loopback address (DNS rebinding). Any IP address is accepted
+// when listening on all interfaces, as rebinding needs a name.
+func checkHost(listen string, next http.Handler) http.Handler {
+	listenHost, _, _ := net.SplitHostPort(listen)
+	listenIP := net.ParseIP(listenHost)
+	anyAddress := listenHost == "" || (listenIP != nil && listenIP.IsUnspecified())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+		ip := net.ParseIP(host)
+		switch {
+		case strings.EqualFold(host, "localhost"), strings.EqualFold(host, listenHost):
+		case ip != nil && (ip.IsLoopback() || anyAddress || ip.Equal(listenIP)):
+		default:
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not the address the API listens on", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests without the bearer token printed at startup,
+// so only clients given the token can read repositories through the API
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token in the Authorization header"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// generateHandler serves POST /generate with generator, which is set up from config
+func generateHandler(config cmd.Config, generator *ollamacommit.Generator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A page can't send JSON to another origin without a preflight, which is never answered
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("the request body must be JSON with Content-Type: application/json"))
+			return
+		}
+
+		var req generateRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestBytes))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+		if req.Diff == "" && req.Repo == "" {
+			writeError(w, http.StatusBadRequest, errors.New("give the changes in diff or the repository in repo"))
+			return
+		}
+		if req.Repo != "" && !filepath.IsAbs(req.Repo) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("repo must be an absolute path, got %q", req.Repo))
+			return
+		}
+
+		opts := ollamacommit.Options{
+			Diff:             req.Diff,
+			Dir:              req.Repo,
+			Paths:            req.Paths,
+			Base:             req.Base,
+			IgnoreWhitespace: req.IgnoreWhitespace,
+			IncludeGenerated: req.IncludeGenerated,
+			Hint:             req.Hint,
+			Model:            config.DefaultModel,
+		}
+		if req.Model != "" {
+			if err := cmd.CheckModelAllowed(req.Model, config.AllowedModels); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+			opts.Model = req.Model
+		}
+		if options, ok := config.ModelDefaults[opts.Model]; ok {
+			opts.ModelOptions = &options
+		}
+
+		message, err := generator.Generate(r.Context(), opts)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, generateResponse{Message: message, Model: opts.Model})
+	}
+}
+
+func runServe(args []string) {
+	config := loadConfig()
+	fs := newFlagSet(findCommand(commands, "serve"), "serve")
+	listen := fs.String("listen", "127.0.0.1:7878", "Address to listen on")
+	fs.IntVar(&config.MaxConcurrency, "max-concurrency", config.MaxConcurrency, "Maximum requests sent to the model server at once, 0 for unlimited")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if err := cmd.CheckModelAllowed(config.DefaultModel, config.AllowedModels); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// One generator for all requests, so they share the limits
+	genConfig := generatorConfig(config)
+	genConfig.MaxConcurrency = config.MaxConcurrency
+	genConfig.SubjectSeparator = config.SubjectSeparator
+	genConfig.SubjectCase = config.SubjectCase
+	genConfig.MaxBodyBullets = config.MaxBodyBullets
+	genConfig.BreakingChangeFooter = config.BreakingChangeFooter
+	genConfig.MaxMessageBytes = config.MaxMessageBytes
+	genConfig.LineEnding = cmd.ResolveLineEnding(config.LineEnding)
+	generator := newGenerator(genConfig)
+	provider := generator.Provider()
+	if err := cmd.CheckHostAllowed(cmd.ProviderURL(provider, config.OllamaAPIURL), config.AllowedHosts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Ask once, since nobody can answer for each request
+	confirmSendingChanges(provider, config.OllamaAPIURL, config)
+
+	if host, _, err := net.SplitHostPort(*listen); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Fprintf(os.Stderr, "Warning: %s is reachable from other machines, which can then read any repository this user can\n", *listen)
+		}
+	}
+
+	token := os.Getenv(serveTokenEnv)
+	if token == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create a token: %v\n", err)
+			os.Exit(1)
+		}
+		token = hex.EncodeToString(random)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /generate", requireToken(token, generateHandler(config, generator)))
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": currentBuild().Version, "model": config.DefaultModel})
+	})
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           checkHost(*listen, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Listening on http://%s (POST /generate, GET /health)\n", *listen)
+	if os.Getenv(serveTokenEnv) == "" {
+		fmt.Printf("Token: %s\n", token)
+	}
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+func runStats(args []string) {
+	fs := newFlagSet(findCommand(commands, "stats"), "stats")
+	repoOnly := fs.Bool("repo", false, "Only count the generations in the current repository")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	records, err := cmd.LoadGenerations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *repoOnly {
+		repo := cmd.CurrentRepo()
+		if repo == "" {
+			fmt.Fprintln(os.Stderr, "Error: not in a git repository")
+			os.Exit(1)
+		}
+		var inRepo []cmd.GenerationRecord
+		for _, record := range records {
+			if record.Repo == repo {
+				inRepo = append(inRepo, record)
+			}
+		}
+		records = inRepo
+	}
+	if len(records) == 0 {
+		fmt.Println("No generations recorded yet")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tGENERATIONS\tACCEPTED\tREJECTED\tACCEPTANCE\tAVG TIME\tAVG DIFF")
+	for _, stats := range cmd.SummarizeGenerations(records) {
+		acceptance := "-"
+		if rate := stats.AcceptanceRate(); rate >= 0 {
+			acceptance = fmt.Sprintf("%.0f%%", rate*100)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n", stats.Model, stats.Generations, stats.Accepted, stats.Rejected,
+			acceptance, stats.AvgLatency.Round(100*time.Millisecond), cmd.FormatSize(int64(stats.AvgDiffSize)))
+	}
+	w.Flush()
+	fmt.Printf("\n%d generations since %s\n", len(records), records[0].Time.Format("2006-01-02"))
+}
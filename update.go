@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mrandiw/ollama-commit/cmd"
+)
+
+func runSelfUpdate(args []string) {
+	fs := newFlagSet(findCommand(commands, "self-update"), "self-update")
+	check := fs.Bool("check", false, "Only report whether a newer release is available")
+	force := fs.Bool("force", false, "Install the latest release even if it isn't newer, or this is a development build")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	release, err := cmd.LatestRelease()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	current := currentBuild().Version
+	development := current == "dev" || cmd.CompareVersions(current, "v0.0.1") < 0
+	newer := !development && cmd.CompareVersions(release.TagName, current) > 0
+	fmt.Printf("Current version: %s\nLatest release:  %s\n", current, release.TagName)
+
+	if *check {
+		switch {
+		case development:
+			fmt.Println("This is a development build; use -force to install the latest release")
+		case newer:
+			fmt.Println("An update is available; run 'ollama-commit self-update' to install it")
+		default:
+			fmt.Println("Already up to date")
+		}
+		return
+	}
+	if !newer && !*force {
+		if development {
+			fmt.Println("This is a development build; use -force to replace it with the latest release")
+		} else {
+			fmt.Println("Already up to date")
+		}
+		return
+	}
+
+	binary, err := cmd.DownloadRelease(release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	path, err := cmd.ReplaceExecutable(binary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated %s to %s\n", path, release.TagName)
+}
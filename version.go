@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at build time with e.g.
+// -ldflags "-X main.version=v1.2.0 -X main.commit=abc1234 -X main.buildDate=2024-05-01T12:00:00Z"
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo describes the running binary
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// currentBuild returns the build metadata, falling back to what the Go
+// toolchain recorded when it wasn't set with -ldflags
+func currentBuild() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if recorded, ok := debug.ReadBuildInfo(); ok {
+		// Binaries installed with go install carry the module version
+		if info.Version == "" && recorded.Main.Version != "" && recorded.Main.Version != "(devel)" {
+			info.Version = recorded.Main.Version
+		}
+		// Binaries built in a checkout carry the commit
+		for _, setting := range recorded.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// versionString describes the build on a single line: the version, the Go
+// version it was built with and the target platform
+func versionString() string {
+	info := currentBuild()
+	return fmt.Sprintf("ollama-commit %s %s %s", info.Version, info.GoVersion, info.Platform)
+}
+
+func runVersion(args []string) {
+	fs := newFlagSet(findCommand(commands, "version"), "version")
+	asJSON := fs.Bool("json", false, "Print the build metadata as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	info := currentBuild()
+	if *asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("ollama-commit %s\n", info.Version)
+	fmt.Printf("Commit:     %s\n", info.Commit)
+	fmt.Printf("Build date: %s\n", info.BuildDate)
+	fmt.Printf("Go version: %s\n", info.GoVersion)
+	fmt.Printf("Platform:   %s\n", info.Platform)
+}